@@ -0,0 +1,329 @@
+package urlverify
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// BlocklistFormat is the syntax of a blocklist file.
+type BlocklistFormat int
+
+const (
+	FormatHosts   BlocklistFormat = iota // Hosts file lines such as "0.0.0.0 evil.example"
+	FormatDomains                        // One domain per line, blocking its subdomains too
+	FormatAdblock                        // Adblock Plus and uBlock network filters such as "||evil.example^"
+)
+
+func (f BlocklistFormat) String() string {
+	switch f {
+	case FormatHosts:
+		return "Hosts"
+	case FormatDomains:
+		return "Domains"
+	case FormatAdblock:
+		return "Adblock"
+	default:
+		return "Unknown"
+	}
+}
+
+// Party restricts a rule to requests from the same site or from other sites, as the
+// Adblock $third-party and $~third-party options do.
+type Party int
+
+const (
+	PartyAny   Party = iota // The rule applies to all requests
+	PartyThird              // The rule only applies when the origin is another site
+	PartyFirst              // The rule only applies when the origin is the same site
+)
+
+// BlocklistRule is a rule loaded into a Blocklist.
+type BlocklistRule struct {
+	Text      string // The rule as written in the file
+	Source    string // Name of the file or reader the rule came from
+	Line      int    // Line number of the rule in Source, starting at 1
+	Pattern   string // The DomainSet pattern the rule matches, see DomainSet
+	Exception bool   // Whether the rule is an Adblock @@ exception
+	Party     Party  // Which requests the rule applies to
+}
+
+// BlocklistMatch is the result of matching a URL against a Blocklist.
+type BlocklistMatch struct {
+	Blocked bool           // Whether the URL is blocked, false if an exception rule matched
+	Rule    *BlocklistRule // The rule that decided
+}
+
+// Blocklist matches URLs and domains against rules loaded from hosts files, domain lists
+// and Adblock network filters. Exception rules win over blocking rules; among rules of
+// the same kind the most specific one is reported. The zero value is empty; matching
+// may run concurrently once loading is done.
+type Blocklist struct {
+	block, allow DomainSet
+	rules        map[string][]*BlocklistRule // By pattern, exceptions prefixed with "@@"
+	skipped      int
+}
+
+// hostsNames are names found in every hosts file that aren't blocking entries.
+var hostsNames = map[string]bool{
+	"localhost": true, "localhost.localdomain": true, "local": true, "broadcasthost": true,
+	"ip6-localhost": true, "ip6-loopback": true, "ip6-localnet": true, "ip6-mcastprefix": true,
+	"ip6-allnodes": true, "ip6-allrouters": true, "ip6-allhosts": true, "0.0.0.0": true,
+}
+
+// LoadBlocklist loads a Blocklist from the given files, all in the same format.
+func LoadBlocklist(format BlocklistFormat, paths ...string) (*Blocklist, error) {
+	b := &Blocklist{}
+	for _, path := range paths {
+		if err := b.LoadFile(path, format); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// LoadFile loads the rules of a blocklist file, reporting path as their Source.
+func (b *Blocklist) LoadFile(path string, format BlocklistFormat) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return b.Load(f, path, format)
+}
+
+// Load loads the rules read from r, reporting source as their Source. Lines that
+// aren't valid rules, such as Adblock filters with paths or unsupported options,
+// are skipped and counted by Skipped.
+func (b *Blocklist) Load(r io.Reader, source string, format BlocklistFormat) error {
+	var parse func(line string) []BlocklistRule
+	switch format {
+	case FormatHosts:
+		parse = parseHostsLine
+	case FormatDomains:
+		parse = parseDomainLine
+	case FormatAdblock:
+		parse = parseAdblockLine
+	default:
+		return fmt.Errorf("unknown blocklist format %d", format)
+	}
+
+	return scanLines(r, source, func(text string, line int) error {
+		rules := parse(text)
+		for i := range rules {
+			rule := &rules[i]
+			rule.Text, rule.Source, rule.Line = text, source, line
+			if !b.add(rule) {
+				b.skipped++
+			}
+		}
+		return nil
+	})
+}
+
+// Len returns the number of rules loaded.
+func (b *Blocklist) Len() int {
+	n := 0
+	for _, rules := range b.rules {
+		n += len(rules)
+	}
+	return n
+}
+
+// Skipped returns the number of rules that were skipped because they couldn't be parsed
+// or aren't supported. Comments and Adblock element hiding rules aren't counted.
+func (b *Blocklist) Skipped() int {
+	return b.skipped
+}
+
+// add adds a parsed rule, an empty pattern marks a line that couldn't be parsed.
+func (b *Blocklist) add(rule *BlocklistRule) bool {
+	set := &b.block
+	if rule.Exception {
+		set = &b.allow
+	}
+	if rule.Pattern == "" || set.Add(rule.Pattern) != nil {
+		return false
+	}
+
+	// Store the rule under the normalized pattern as returned by lookups
+	rule.Pattern = normalizePattern(rule.Pattern)
+	key := rule.Pattern
+	if rule.Exception {
+		key = "@@" + key
+	}
+	if b.rules == nil {
+		b.rules = make(map[string][]*BlocklistRule)
+	}
+	b.rules[key] = append(b.rules[key], rule)
+	return true
+}
+
+// Match matches a URL or domain against the rules. origin is the URL or domain of the
+// page the URL was found on, used for third-party rules; if it is empty, the rules
+// apply regardless of their party. ok is false if no rule matched.
+func (b *Blocklist) Match(raw, origin string) (match BlocklistMatch, ok bool) {
+	result := ValidateDomain(raw)
+	if !result.Valid || result.ASCIIHost == "" {
+		return BlocklistMatch{}, false
+	}
+	return b.MatchHost(result.ASCIIHost, origin)
+}
+
+// MatchHost works like Match for a host name.
+func (b *Blocklist) MatchHost(host, origin string) (match BlocklistMatch, ok bool) {
+	party := PartyAny
+	if origin != "" {
+		party = partyOf(host, origin)
+	}
+
+	if rule := b.lookup(&b.allow, "@@", host, party); rule != nil {
+		return BlocklistMatch{Blocked: false, Rule: rule}, true
+	}
+	if rule := b.lookup(&b.block, "", host, party); rule != nil {
+		return BlocklistMatch{Blocked: true, Rule: rule}, true
+	}
+	return BlocklistMatch{}, false
+}
+
+// lookup returns the most specific rule in set matching host that applies to party.
+func (b *Blocklist) lookup(set *DomainSet, prefix, host string, party Party) *BlocklistRule {
+	for _, pattern := range set.lookupAll(host) {
+		for _, rule := range b.rules[prefix+pattern] {
+			if rule.Party == PartyAny || party == PartyAny || rule.Party == party {
+				return rule
+			}
+		}
+	}
+	return nil
+}
+
+// partyOf tells whether a request to host from origin is first or third party,
+// comparing registrable domains.
+func partyOf(host, origin string) Party {
+	result := ValidateDomain(origin)
+	if !result.Valid {
+		return PartyThird
+	}
+	if site(host) == site(result.ASCIIHost) {
+		return PartyFirst
+	}
+	return PartyThird
+}
+
+func site(host string) string {
+	if s, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return s
+	}
+	return host
+}
+
+// normalizePattern normalizes the name of a DomainSet pattern already known to be valid.
+func normalizePattern(pattern string) string {
+	prefix := ""
+	if strings.HasPrefix(pattern, "*.") {
+		prefix, pattern = "*.", pattern[2:]
+	} else if strings.HasPrefix(pattern, ".") {
+		prefix, pattern = ".", pattern[1:]
+	}
+	name, _ := normalizeSetName(pattern)
+	return prefix + name
+}
+
+// parseHostsLine parses a hosts file line, "0.0.0.0 evil.example other.example # comment".
+// Hosts file entries block the exact names.
+func parseHostsLine(line string) []BlocklistRule {
+	line, _, _ = strings.Cut(line, "#")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	if net.ParseIP(fields[0]) == nil || len(fields) == 1 {
+		return []BlocklistRule{{}}
+	}
+
+	var rules []BlocklistRule
+	for _, name := range fields[1:] {
+		if hostsNames[strings.ToLower(name)] {
+			continue
+		}
+		rules = append(rules, BlocklistRule{Pattern: name})
+	}
+	return rules
+}
+
+// parseDomainLine parses a domain list line. A plain domain blocks the domain and its
+// subdomains, DomainSet patterns are used as written.
+func parseDomainLine(line string) []BlocklistRule {
+	line, _, _ = strings.Cut(line, "#")
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	if strings.ContainsAny(line, " \t") {
+		return []BlocklistRule{{}}
+	}
+	if !strings.HasPrefix(line, ".") && !strings.HasPrefix(line, "*.") {
+		line = "." + line
+	}
+	return []BlocklistRule{{Pattern: line}}
+}
+
+// parseAdblockLine parses an Adblock network filter. Only domain anchored filters,
+// "||evil.example^" optionally with the $third-party option or its negation, are
+// supported; "@@" makes them exceptions.
+func parseAdblockLine(line string) []BlocklistRule {
+	if line == "" || line[0] == '!' || line[0] == '[' {
+		return nil
+	}
+	if strings.Contains(line, "##") || strings.Contains(line, "#@#") || strings.Contains(line, "#?#") || strings.Contains(line, "#$#") {
+		return nil // Element hiding
+	}
+
+	var rule BlocklistRule
+	body, ok := strings.CutPrefix(line, "@@")
+	rule.Exception = ok
+
+	if i := strings.LastIndexByte(body, '$'); i >= 0 {
+		party, ok := parseAdblockOptions(body[i+1:])
+		if !ok {
+			return []BlocklistRule{{}}
+		}
+		rule.Party, body = party, body[:i]
+	}
+
+	host, ok := strings.CutPrefix(body, "||")
+	if !ok {
+		return []BlocklistRule{{}}
+	}
+	host = strings.TrimSuffix(strings.TrimSuffix(host, "|"), "^")
+	if host == "" || strings.ContainsAny(host, "/*^|?=&:") {
+		return []BlocklistRule{{}}
+	}
+
+	rule.Pattern = "." + host
+	return []BlocklistRule{rule}
+}
+
+// parseAdblockOptions parses the options of a network filter. Options that only
+// affect how a request is made don't change which domains are blocked and are
+// ignored; ok is false for options that narrow the rule in ways a URL can't tell,
+// such as resource types or $domain=.
+func parseAdblockOptions(options string) (party Party, ok bool) {
+	for _, opt := range strings.Split(options, ",") {
+		switch strings.ToLower(strings.TrimSpace(opt)) {
+		case "third-party", "3p", "~first-party", "~1p":
+			party = PartyThird
+		case "~third-party", "~3p", "first-party", "1p":
+			party = PartyFirst
+		case "important", "all", "document", "doc", "popup":
+		default:
+			return PartyAny, false
+		}
+	}
+	return party, true
+}
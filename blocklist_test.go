@@ -0,0 +1,180 @@
+package urlverify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testHosts = `# Malware hosts
+127.0.0.1 localhost
+::1 localhost ip6-localhost
+0.0.0.0 evil.example.com   tracker.example.net # two names
+
+0.0.0.0 0.0.0.0
+not-an-ip bad.example.org
+`
+
+const testDomains = `# One per line
+phish.example.com
+*.cdn.example.org
+
+bad domain.example
+`
+
+const testAdblock = `[Adblock Plus 2.0]
+! Title: test list
+||ads.example.com^
+||tracker.example.net^$third-party
+||analytics.example.io^$~third-party
+@@||good.ads.example.com^
+||example.org/banner.gif
+||script.example.com^$script
+example.com##.banner
+||xn--80afohp.xn--p1ai^
+`
+
+func TestBlocklistHosts(t *testing.T) {
+	var b Blocklist
+	if err := b.Load(strings.NewReader(testHosts), "hosts.txt", FormatHosts); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 2 || b.Skipped() != 1 {
+		t.Errorf("Len() = %d, Skipped() = %d, want 2, 1", b.Len(), b.Skipped())
+	}
+
+	m, ok := b.Match("https://evil.example.com/login", "")
+	if !ok || !m.Blocked {
+		t.Fatalf("Match(evil.example.com) = %+v, %v, want blocked", m, ok)
+	}
+	if m.Rule.Source != "hosts.txt" || m.Rule.Line != 4 || m.Rule.Pattern != "evil.example.com" {
+		t.Errorf("Match(evil.example.com) rule = %+v", m.Rule)
+	}
+	if !strings.HasPrefix(m.Rule.Text, "0.0.0.0 evil.example.com") {
+		t.Errorf("rule text = %q", m.Rule.Text)
+	}
+
+	// Hosts entries only block the exact name
+	if _, ok := b.Match("www.evil.example.com", ""); ok {
+		t.Error("hosts entry matched a subdomain")
+	}
+	if _, ok := b.Match("localhost", ""); ok {
+		t.Error("localhost matched")
+	}
+}
+
+func TestBlocklistDomains(t *testing.T) {
+	var b Blocklist
+	if err := b.Load(strings.NewReader(testDomains), "domains.txt", FormatDomains); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 2 || b.Skipped() != 1 {
+		t.Errorf("Len() = %d, Skipped() = %d, want 2, 1", b.Len(), b.Skipped())
+	}
+
+	tests := []struct {
+		input   string
+		line    int
+		pattern string
+	}{
+		{"phish.example.com", 2, ".phish.example.com"},
+		{"https://login.phish.example.com/", 2, ".phish.example.com"},
+		{"a.cdn.example.org", 3, "*.cdn.example.org"},
+		{"cdn.example.org", 0, ""},
+		{"example.com", 0, ""},
+	}
+	for _, tt := range tests {
+		m, ok := b.Match(tt.input, "")
+		if ok != (tt.line != 0) {
+			t.Errorf("Match(%q) ok = %v", tt.input, ok)
+			continue
+		}
+		if ok && (m.Rule.Line != tt.line || m.Rule.Pattern != tt.pattern) {
+			t.Errorf("Match(%q) = line %d %q, want line %d %q", tt.input, m.Rule.Line, m.Rule.Pattern, tt.line, tt.pattern)
+		}
+	}
+}
+
+func TestBlocklistAdblock(t *testing.T) {
+	var b Blocklist
+	if err := b.Load(strings.NewReader(testAdblock), "easylist.txt", FormatAdblock); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 5 || b.Skipped() != 2 {
+		t.Errorf("Len() = %d, Skipped() = %d, want 5, 2", b.Len(), b.Skipped())
+	}
+
+	tests := []struct {
+		input, origin string
+		matched       bool
+		blocked       bool
+		line          int
+	}{
+		{"https://ads.example.com/x.js", "", true, true, 3},
+		{"https://cdn.ads.example.com/x.js", "https://news.example.org", true, true, 3},
+		{"https://good.ads.example.com/", "", true, false, 6},
+		{"https://www.good.ads.example.com/", "", true, false, 6},
+		{"https://tracker.example.net/p", "https://news.example.org/", true, true, 4},
+		{"https://tracker.example.net/p", "https://www.example.net/", false, false, 0},
+		{"https://tracker.example.net/p", "", true, true, 4},
+		{"https://analytics.example.io/", "https://example.io/", true, true, 5},
+		{"https://analytics.example.io/", "https://example.com/", false, false, 0},
+		{"https://script.example.com/", "", false, false, 0},
+		{"https://книга.рф/", "", true, true, 10},
+		{"https://example.com/", "", false, false, 0},
+	}
+	for _, tt := range tests {
+		m, ok := b.Match(tt.input, tt.origin)
+		if ok != tt.matched || m.Blocked != tt.blocked {
+			t.Errorf("Match(%q, %q) = %v blocked %v, want %v blocked %v", tt.input, tt.origin, ok, m.Blocked, tt.matched, tt.blocked)
+			continue
+		}
+		if ok && m.Rule.Line != tt.line {
+			t.Errorf("Match(%q, %q) line = %d (%q), want %d", tt.input, tt.origin, m.Rule.Line, m.Rule.Text, tt.line)
+		}
+	}
+
+	if m, _ := b.Match("good.ads.example.com", ""); !m.Rule.Exception || m.Rule.Text != "@@||good.ads.example.com^" {
+		t.Errorf("exception rule = %+v", m.Rule)
+	}
+}
+
+func TestLoadBlocklistFiles(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	os.WriteFile(first, []byte("one.example.com\n"), 0o644)
+	os.WriteFile(second, []byte("# second\ntwo.example.com\n"), 0o644)
+
+	b, err := LoadBlocklist(FormatDomains, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range ExtractAll("see one.example.com and https://two.example.com/x") {
+		m, ok := b.Match(url, "")
+		if !ok || !m.Blocked {
+			t.Errorf("Match(%q) not blocked", url)
+		}
+	}
+	if m, _ := b.Match("two.example.com", ""); m.Rule.Source != second || m.Rule.Line != 2 {
+		t.Errorf("rule = %+v, want %s line 2", m.Rule, second)
+	}
+
+	if _, err := LoadBlocklist(FormatDomains, filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("LoadBlocklist of a missing file succeeded")
+	}
+}
+
+func TestBlocklistLongLine(t *testing.T) {
+	var b Blocklist
+	text := "! " + strings.Repeat("x", 2<<20) + "\n||ads.example.com^\n"
+	if err := b.Load(strings.NewReader(text), "long.txt", FormatAdblock); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := b.Match("https://ads.example.com/", ""); !ok || m.Rule.Line != 2 {
+		t.Errorf("Match = %+v, %v, want the rule on line 2", m, ok)
+	}
+	if err := b.Load(strings.NewReader(""), "empty.txt", BlocklistFormat(99)); err == nil {
+		t.Error("Load with an unknown format succeeded")
+	}
+}
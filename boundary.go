@@ -0,0 +1,138 @@
+package urlverify
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// spacelessScripts are scripts usually written without spaces between words, so a URL
+// running into them from ASCII has most likely ended.
+var spacelessScripts = []*unicode.RangeTable{
+	unicode.Han,
+	unicode.Hiragana,
+	unicode.Katakana,
+	unicode.Hangul,
+	unicode.Thai,
+	unicode.Lao,
+	unicode.Khmer,
+	unicode.Myanmar,
+}
+
+// urlBounds returns the part of text that belongs to the URL or domain the regex matched
+// as text[:n]. URLs with a scheme, and bare domains followed by a path, run on up to
+// whitespace unless a boundary comes first, so that the regex doesn't have to scan text
+// past the end of the URL. A bare domain glued to preceding CJK or Thai text, as in
+// "詳細はexample.com", starts at its ASCII part.
+func urlBounds(text string, n int) (start, end int) {
+	hostStart, extend := 0, false
+	if i := strings.Index(text[:min(n, len("https://"))], "://"); i >= 0 {
+		hostStart, extend = i+len("://"), true
+	} else {
+		start = bareDomainStart(text[:n])
+		extend = n < len(text) && text[n] == '/'
+	}
+
+	b := newURLBoundary(hostStart)
+	for i, r := range text[start:] {
+		pos := start + i
+		if pos >= n && (!extend || isRegexSpace(r)) {
+			return start, pos
+		}
+		if b.ends(pos, r, text[pos+utf8.RuneLen(r):]) {
+			return start, pos
+		}
+	}
+
+	return start, len(text)
+}
+
+// urlBoundary finds the end of a URL fed to it one rune at a time. The URL ends at bidi
+// control characters, at non-ASCII punctuation and spaces such as "、", "。", "「" or "»"
+// (the IDNA full stops are allowed between host labels), and where ASCII runs into a
+// script written without spaces, as in "https://example.com/をご覧ください". The last
+// rule doesn't apply to the path of URLs with an internationalized host.
+type urlBoundary struct {
+	hostStart   int
+	inHost      bool
+	unicodeHost bool
+	prev        rune
+}
+
+func newURLBoundary(hostStart int) urlBoundary {
+	return urlBoundary{hostStart: hostStart, inHost: true, prev: -1}
+}
+
+// ends reports whether the URL ends before r at pos. rest is the text following r.
+func (b *urlBoundary) ends(pos int, r rune, rest string) bool {
+	if b.inHost && pos >= b.hostStart && (r == '/' || r == '?' || r == '#') {
+		b.inHost = false
+	}
+	if r < utf8.RuneSelf {
+		b.prev = r
+		return false
+	}
+
+	switch {
+	case unicode.Is(unicode.Bidi_Control, r):
+		return true
+	case unicode.IsPunct(r) || unicode.IsSpace(r):
+		if !b.inHost || !isIDNADot(r) || !startsLabel(rest) {
+			return true
+		}
+	case b.prev >= 0 && b.prev < utf8.RuneSelf && isSpacelessScript(r) && (b.inHost || !b.unicodeHost):
+		if pos > b.hostStart {
+			return true
+		}
+	}
+
+	if b.inHost && pos >= b.hostStart && unicode.IsLetter(r) {
+		b.unicodeHost = true
+	}
+	b.prev = r
+	return false
+}
+
+// bareDomainStart returns the position in the first label of a bare domain where
+// ASCII follows a script written without spaces, or 0.
+func bareDomainStart(raw string) int {
+	start, prev := 0, rune(-1)
+	for i, r := range raw {
+		if r == '.' || isIDNADot(r) {
+			break
+		}
+		if prev >= 0 && isSpacelessScript(prev) && r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			start = i
+		}
+		prev = r
+	}
+	return start
+}
+
+// startsLabel reports whether s starts with a letter or digit that can begin a domain label.
+func startsLabel(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isSpacelessScript(r rune) bool {
+	return r >= utf8.RuneSelf && unicode.IsOneOf(spacelessScripts, r)
+}
+
+// isRegexSpace reports whether r is matched by \s in urlRegex.
+func isRegexSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\f' || r == '\r'
+}
+
+func isIDNADot(r rune) bool {
+	return r == '。' || r == '．' || r == '｡'
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package urlverify
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractAllMultilingual(t *testing.T) {
+	tests := []struct {
+		text        string
+		expected    []string
+		description string
+	}{
+		{"詳細はhttps://example.com/をご覧ください。", []string{"https://example.com/"}, "Japanese sentence"},
+		{"請訪問https://example.com/path，謝謝", []string{"https://example.com/path"}, "Chinese full-width comma"},
+		{"公式サイト「https://example.jp/news」を参照", []string{"https://example.jp/news"}, "Japanese corner brackets"},
+		{"詳細はexample.comをご覧ください", []string{"example.com"}, "bare domain inside Japanese text"},
+		{"자세한 내용은 https://example.kr/docs에서 확인하세요", []string{"https://example.kr/docs"}, "Korean particle after path"},
+		{"ดูข้อมูลที่https://example.co.th/pathเพิ่มเติม", []string{"https://example.co.th/path"}, "Thai text"},
+		{"https://例え.jp/ページ/一覧 を見る", []string{"https://例え.jp/ページ/一覧"}, "internationalized host keeps Unicode path"},
+		{"زوروا ‏https://example.com/path‏ للمزيد", []string{"https://example.com/path"}, "Arabic with RLM marks"},
+		{"راجع https://example.com/a،https://example.org/b؟", []string{"https://example.com/a", "https://example.org/b"}, "Arabic comma and question mark"},
+		{"Siehe «https://example.de/seite» für mehr", []string{"https://example.de/seite"}, "guillemets"},
+		{"二つのリンク：https://a.example.com、https://b.example.com。", []string{"https://a.example.com", "https://b.example.com"}, "two URLs separated by CJK punctuation"},
+		{"資料はexample.org/docsを参照", []string{"example.org/docs"}, "bare domain path inside Japanese text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			result := ExtractAll(tt.text)
+			if !slices.Equal(result, tt.expected) {
+				t.Errorf("ExtractAll(%q) = %q, want %q", tt.text, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractAllSpacelessText(t *testing.T) {
+	text := strings.Repeat("詳しくはhttps://example.com/pageをご覧ください。", 2000)
+	result := ExtractAll(text)
+	if len(result) != 2000 || result[0] != "https://example.com/page" {
+		t.Errorf("ExtractAll found %d URLs, first %q", len(result), result[:min(len(result), 1)])
+	}
+}
+
+// BenchmarkExtractAll_SpacelessText measures text without whitespace, where a URL ends
+// at a script boundary rather than at a space.
+func BenchmarkExtractAll_SpacelessText(b *testing.B) {
+	text := strings.Repeat("詳しくはhttps://example.com/pageをご覧ください。また資料はexample.org/docsを参照、", 500)
+	for i := 0; i < b.N; i++ {
+		ExtractAll(text)
+	}
+}
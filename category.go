@@ -0,0 +1,117 @@
+package urlverify
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Category is a kind of site a domain belongs to. Data files loaded into a Categorizer
+// may use categories besides the built-in ones.
+type Category string
+
+const (
+	CategoryPaste        Category = "paste"         // Paste sites such as pastebin.com
+	CategoryFileHost     Category = "file-host"     // File hosting and transfer such as mediafire.com
+	CategorySocial       Category = "social"        // Social networks and messengers such as reddit.com
+	CategoryShortener    Category = "shortener"     // URL shorteners such as bit.ly
+	CategoryDynamicDNS   Category = "dynamic-dns"   // Dynamic DNS providers such as duckdns.org
+	CategoryCloudStorage Category = "cloud-storage" // Cloud storage such as dropbox.com
+	CategoryCodeHost     Category = "code-host"     // Code hosting such as github.com
+)
+
+//go:embed data/categories.txt
+var builtinCategories string
+
+// defaultCategorizer is built on first use, not every program needs it.
+var defaultCategorizer = sync.OnceValue(NewCategorizer)
+
+// Categorizer maps domains to the categories of the sites they belong to. A host gets
+// the categories of the most specific listed domain it is or is below, which may be
+// a registrable domain such as pastebin.com, a subdomain such as docs.google.com or
+// a private public suffix such as duckdns.org. The zero value is empty; a loaded
+// Categorizer may be shared between goroutines.
+type Categorizer struct {
+	domains map[string][]Category
+}
+
+// NewCategorizer creates a Categorizer with the built-in classification data, the
+// data/categories.txt file of the package and the URL shortener list.
+func NewCategorizer() *Categorizer {
+	c := &Categorizer{}
+	if err := c.Load(strings.NewReader(builtinCategories), "data/categories.txt"); err != nil {
+		panic(err)
+	}
+	for domain := range shortenerDomains {
+		c.Add(domain, CategoryShortener)
+	}
+	return c
+}
+
+// LoadCategorizer creates a Categorizer with the built-in data and the categories
+// loaded from the given files, see Categorizer.Load.
+func LoadCategorizer(paths ...string) (*Categorizer, error) {
+	c := NewCategorizer()
+	if err := loadFiles(paths, c.Load); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load adds the categories read from r in the format of the built-in data file: a
+// "[category]" header followed by the domains in the category, one per line. Blank
+// lines and comments starting with '#' are ignored. Errors are reported with source
+// and the line number.
+func (c *Categorizer) Load(r io.Reader, source string) error {
+	return readSections(r, source, "category", "domain", func(name, domain string) error {
+		return c.Add(domain, Category(strings.ToLower(name)))
+	})
+}
+
+// Add adds categories to a domain and its subdomains.
+func (c *Categorizer) Add(domain string, categories ...Category) error {
+	name, err := normalizeSetName(domain)
+	if err != nil {
+		return fmt.Errorf("category domain %q: %w", domain, err)
+	}
+	if c.domains == nil {
+		c.domains = make(map[string][]Category)
+	}
+	for _, category := range categories {
+		if !slices.Contains(c.domains[name], category) {
+			c.domains[name] = append(c.domains[name], category)
+		}
+	}
+	return nil
+}
+
+// Categorize returns the categories of the host of a URL or domain, nil if it isn't
+// listed.
+func (c *Categorizer) Categorize(raw string) []Category {
+	result := ValidateDomain(raw)
+	if !result.Valid || result.ASCIIHost == "" {
+		return nil
+	}
+	return c.categorizeHost(result.ASCIIHost)
+}
+
+// Categorize returns the categories of the host of a URL or domain using the
+// built-in data, see Categorizer.
+func Categorize(raw string) []Category {
+	return defaultCategorizer().Categorize(raw)
+}
+
+// categorizeHost returns the categories of the most specific listed suffix of an
+// ASCII host.
+func (c *Categorizer) categorizeHost(host string) []Category {
+	for name := host; name != ""; {
+		if categories, ok := c.domains[name]; ok {
+			return slices.Clone(categories)
+		}
+		_, name, _ = strings.Cut(name, ".")
+	}
+	return nil
+}
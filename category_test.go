@@ -0,0 +1,100 @@
+package urlverify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Category
+	}{
+		{"https://pastebin.com/raw/abc", []Category{CategoryPaste}},
+		{"paste.mozilla.org/xyz", []Category{CategoryPaste}},
+		{"https://mozilla.org/", nil},
+		{"https://download1.mediafire.com/file", []Category{CategoryFileHost}},
+		{"https://www.reddit.com/r/golang", []Category{CategorySocial}},
+		{"https://bit.ly/3xYz", []Category{CategoryShortener}},
+		{"https://myhome.duckdns.org/", []Category{CategoryDynamicDNS}},
+		{"http://office.dyndns.org:8080/", []Category{CategoryDynamicDNS}},
+		{"https://docs.google.com/document/d/1", []Category{CategoryCloudStorage}},
+		{"https://www.google.com/", nil},
+		{"https://bucket.s3.amazonaws.com/key", []Category{CategoryCloudStorage}},
+		{"https://raw.githubusercontent.com/a/b/main/x.sh", []Category{CategoryCodeHost}},
+		{"https://user.github.io/", []Category{CategoryCodeHost}},
+		{"https://example.com/", nil},
+		{"http://192.0.2.1/", nil},
+		{"not a domain", nil},
+	}
+	for _, tt := range tests {
+		if got := Categorize(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Categorize(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	// The dynamic DNS suffix is a private public suffix
+	if r := ValidateDomain("myhome.duckdns.org"); r.Type != URLTypeNonICANN {
+		t.Errorf("duckdns.org type = %v, want %v", r.Type, URLTypeNonICANN)
+	}
+}
+
+func TestCategorizerLoad(t *testing.T) {
+	var c Categorizer
+	data := "# custom\n[phishing]\nevil.example.com\n\n[Social]\nsocial.example.net # ours\n[phishing]\nsocial.example.net\n"
+	if err := c.Load(strings.NewReader(data), "custom.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Categorize("https://login.evil.example.com/"); !reflect.DeepEqual(got, []Category{"phishing"}) {
+		t.Errorf("Categorize(evil) = %v", got)
+	}
+	if got := c.Categorize("social.example.net"); !reflect.DeepEqual(got, []Category{CategorySocial, "phishing"}) {
+		t.Errorf("Categorize(social) = %v", got)
+	}
+	if got := c.Categorize("pastebin.com"); got != nil {
+		t.Errorf("empty Categorizer returned built-in categories %v", got)
+	}
+
+	for _, bad := range []string{"example.com\n", "[paste\nexample.com\n", "[]\n", "[paste]\nexample..com\n"} {
+		err := c.Load(strings.NewReader(bad), "bad.txt")
+		if err == nil || !strings.HasPrefix(err.Error(), "bad.txt:") {
+			t.Errorf("Load(%q) error = %v", bad, err)
+		}
+	}
+}
+
+func TestLoadCategorizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "extra.txt")
+	os.WriteFile(path, []byte("[paste]\npaste.example.com\n[code-host]\npastebin.com\n"), 0o644)
+
+	c, err := LoadCategorizer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Categorize("paste.example.com"); !reflect.DeepEqual(got, []Category{CategoryPaste}) {
+		t.Errorf("Categorize(loaded) = %v", got)
+	}
+	if got := c.Categorize("pastebin.com"); !reflect.DeepEqual(got, []Category{CategoryPaste, CategoryCodeHost}) {
+		t.Errorf("Categorize(merged) = %v", got)
+	}
+	if _, err := LoadCategorizer(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadCategorizer of a missing file succeeded")
+	}
+}
+
+func TestExtractorCategorizer(t *testing.T) {
+	text := "Payload at https://pastebin.com/raw/abc, mirror on github.com and example.org"
+	matches := NewExtractor(WithCategorizer(nil)).FindAll(text)
+	if len(matches) != 3 {
+		t.Fatalf("FindAll() returned %d matches, want 3", len(matches))
+	}
+	want := [][]Category{{CategoryPaste}, {CategoryCodeHost}, nil}
+	for i, m := range matches {
+		if !reflect.DeepEqual(m.Categories, want[i]) {
+			t.Errorf("%s categories = %v, want %v", m.Text, m.Categories, want[i])
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package urlverify
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Signal is a heuristic hint about whether a match is really meant as a URL or domain.
+type Signal int
+
+const (
+	SignalScheme        Signal = iota // Starts with http:// or https://
+	SignalWWW                         // Host starts with www.
+	SignalPath                        // Has a path, query or fragment
+	SignalPort                        // Has an explicit port
+	SignalIP                          // Host is an IP address
+	SignalURLContext                  // Right after words such as "visit" or "website"
+	SignalFileExtension               // TLD doubles as a common file extension, e.g. report.zip
+	SignalVersionNumber               // IPv4-looking version string, e.g. "version 1.2.3.4"
+	SignalCapitalized                 // Upper-case letters in a bare domain, e.g. ASP.NET
+	SignalTechName                    // Known technology name, e.g. Socket.IO
+	SignalFileContext                 // Near words such as "file", "download" or "run"
+	SignalEmailDomain                 // Domain part of an email address
+	SignalAbbreviation                // Starts with an abbreviation, e.g. "e.g.example.com"
+)
+
+func (s Signal) String() string {
+	switch s {
+	case SignalScheme:
+		return "Scheme"
+	case SignalWWW:
+		return "WWW"
+	case SignalPath:
+		return "Path"
+	case SignalPort:
+		return "Port"
+	case SignalIP:
+		return "IP"
+	case SignalURLContext:
+		return "URL Context"
+	case SignalFileExtension:
+		return "File Extension TLD"
+	case SignalVersionNumber:
+		return "Version Number"
+	case SignalCapitalized:
+		return "Capitalized"
+	case SignalTechName:
+		return "Technology Name"
+	case SignalFileContext:
+		return "File Context"
+	case SignalEmailDomain:
+		return "Email Domain"
+	case SignalAbbreviation:
+		return "Abbreviation"
+	default:
+		return "Unknown"
+	}
+}
+
+// signalWeights is how much each signal moves the confidence away from baseConfidence.
+var signalWeights = map[Signal]float64{
+	SignalScheme:        0.5,
+	SignalWWW:           0.3,
+	SignalPath:          0.15,
+	SignalPort:          0.1,
+	SignalIP:            0,
+	SignalURLContext:    0.2,
+	SignalFileExtension: -0.35,
+	SignalVersionNumber: -0.6,
+	SignalCapitalized:   -0.2,
+	SignalTechName:      -0.5,
+	SignalFileContext:   -0.2,
+	SignalEmailDomain:   -0.1,
+	SignalAbbreviation:  -0.4,
+}
+
+const baseConfidence = 0.5
+
+// fileExtensionTLDs are TLDs that are also common file extensions.
+var fileExtensionTLDs = map[string]bool{
+	"zip": true, "mov": true, "py": true, "pl": true, "pm": true, "sh": true,
+	"rs": true, "md": true, "ps": true, "ai": true, "cc": true, "so": true,
+	"tf": true, "ml": true, "java": true, "cab": true,
+}
+
+// techNames are technology names that happen to be valid domains.
+var techNames = map[string]bool{
+	"asp.net": true, "vb.net": true, "ado.net": true, "dot.net": true,
+	"socket.io": true, "ionic.io": true,
+}
+
+// urlContextWords are words that announce a URL when they come right before it.
+// Short and common words such as "go" or "at" are left out, they appear in front
+// of file names and in dotted names too often.
+var urlContextWords = map[string]bool{
+	"visit": true, "website": true, "site": true, "link": true, "url": true,
+	"homepage": true, "goto": true, "browse": true, "domain": true,
+}
+
+var fileContextWords = map[string]bool{
+	"file": true, "files": true, "download": true, "downloaded": true, "attachment": true,
+	"attached": true, "run": true, "execute": true, "save": true, "saved": true,
+	"script": true, "module": true, "package": true, "import": true, "edit": true,
+	"rename": true, "archive": true, "folder": true, "directory": true,
+}
+
+var abbreviationPrefixes = []string{"e.g.", "i.e.", "etc.", "cf.", "vs.", "viz."}
+
+var versionContextWords = map[string]bool{
+	"version": true, "ver": true, "v": true, "release": true, "build": true,
+	"rev": true, "revision": true, "firmware": true, "update": true,
+}
+
+// scoreMatches sets Confidence and Signals on all matches found in text.
+func scoreMatches(text string, matches []Match) {
+	for i := range matches {
+		scoreMatch(text, &matches[i])
+	}
+}
+
+// scoreMatch sets Confidence and Signals on a match found in text.
+func scoreMatch(text string, m *Match) {
+	add := func(s Signal) {
+		m.Signals = append(m.Signals, s)
+	}
+
+	raw := m.Text
+	lower := strings.ToLower(raw)
+	bare := true
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		add(SignalScheme)
+		bare = false
+	}
+
+	u := m.Result.URL
+	host := ""
+	if u != nil {
+		host = u.Hostname()
+		if strings.HasPrefix(strings.ToLower(host), "www.") {
+			add(SignalWWW)
+			bare = false
+		}
+		if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			add(SignalPath)
+		}
+		if u.Port() != "" {
+			add(SignalPort)
+		}
+	}
+
+	before, after := wordsBefore(text, m.Start, 3), wordsAfter(text, m.End, 2)
+	if m.Start > 0 && text[m.Start-1] == '@' {
+		add(SignalEmailDomain)
+	}
+
+	if m.Result.Type == URLTypeIP {
+		add(SignalIP)
+		if bare && isVersionContext(text, m, before) {
+			add(SignalVersionNumber)
+		}
+	} else if bare {
+		if fileExtensionTLDs[strings.ToLower(m.Result.TLD)] && !strings.Contains(m.Result.TLD, ".") {
+			add(SignalFileExtension)
+		}
+		if techNames[strings.ToLower(host)] {
+			add(SignalTechName)
+		}
+		if strings.IndexFunc(host, unicode.IsUpper) >= 0 {
+			add(SignalCapitalized)
+		}
+		for _, prefix := range abbreviationPrefixes {
+			if strings.HasPrefix(lower, prefix) {
+				add(SignalAbbreviation)
+				break
+			}
+		}
+	}
+
+	if urlContextWords[precedingWord(text, m.Start)] {
+		add(SignalURLContext)
+	}
+	for _, w := range append(before, after...) {
+		if fileContextWords[w] {
+			add(SignalFileContext)
+			break
+		}
+	}
+
+	score := baseConfidence
+	for _, s := range m.Signals {
+		score += signalWeights[s]
+	}
+	m.Confidence = min(max(score, 0), 1)
+}
+
+// isVersionContext reports whether an IPv4-looking match is more likely a version number.
+func isVersionContext(text string, m *Match, before []string) bool {
+	// 1.2.3.4.5 matches as 1.2.3.4
+	if m.End+1 < len(text) && text[m.End] == '.' && isDigit(text[m.End+1]) {
+		return true
+	}
+	for _, w := range before {
+		if versionContextWords[w] {
+			return true
+		}
+	}
+	// Leading zeros are unusual in addresses but common in versions like 1.02.03.04
+	for _, part := range strings.Split(m.Text, ".") {
+		if len(part) > 1 && part[0] == '0' {
+			return true
+		}
+	}
+	return false
+}
+
+// wordsBefore returns up to n lower-cased words preceding position pos in text, nearest first.
+func wordsBefore(text string, pos, n int) []string {
+	fields := strings.FieldsFunc(text[max(0, pos-64):pos], isWordSeparator)
+	var words []string
+	for i := len(fields) - 1; i >= 0 && len(words) < n; i-- {
+		words = append(words, strings.ToLower(fields[i]))
+	}
+	return words
+}
+
+// precedingWord returns the lower-cased word separated from position pos in text only
+// by spaces or a colon, empty if there is none. Words that end a dotted name, such as
+// "go" in "main.go", don't count.
+func precedingWord(text string, pos int) string {
+	head := strings.TrimRight(text[max(0, pos-64):pos], " \t:")
+	i := strings.LastIndexFunc(head, isWordSeparator)
+	if i >= 0 && head[i] == '.' {
+		return ""
+	}
+	if i >= 0 {
+		_, size := utf8.DecodeRuneInString(head[i:])
+		i += size
+	}
+	return strings.ToLower(head[max(i, 0):])
+}
+
+// wordsAfter returns up to n lower-cased words following position pos in text.
+func wordsAfter(text string, pos, n int) []string {
+	fields := strings.FieldsFunc(text[pos:min(len(text), pos+64)], isWordSeparator)
+	var words []string
+	for i := 0; i < len(fields) && len(words) < n; i++ {
+		words = append(words, strings.ToLower(fields[i]))
+	}
+	return words
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,77 @@
+package urlverify
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConfidenceSignals(t *testing.T) {
+	tests := []struct {
+		text        string
+		match       string
+		signal      Signal
+		description string
+	}{
+		{"Visit https://example.com/docs today", "https://example.com/docs", SignalScheme, "scheme"},
+		{"Go to www.example.com now", "www.example.com", SignalWWW, "www prefix"},
+		{"See example.com/index.html for details", "example.com/index.html", SignalPath, "path"},
+		{"Please download report.zip from the share", "report.zip", SignalFileExtension, "file extension TLD"},
+		{"Run setup.py to install", "setup.py", SignalFileContext, "file context"},
+		{"Upgraded to version 1.2.3.4 yesterday", "1.2.3.4", SignalVersionNumber, "version word"},
+		{"Released 1.2.3.4.5 yesterday", "1.2.3.4", SignalVersionNumber, "trailing version component"},
+		{"Built with ASP.NET and React", "ASP.NET", SignalTechName, "technology name"},
+		{"Built with ASP.NET and React", "ASP.NET", SignalCapitalized, "capitalized"},
+		{"Mail me at user@example.org please", "example.org", SignalEmailDomain, "email domain"},
+		{"Visit example.com today", "example.com", SignalURLContext, "URL context"},
+		{"Our website: example.com", "example.com", SignalURLContext, "URL context before a colon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			var found *Match
+			matches := FindAll(tt.text)
+			for i := range matches {
+				if matches[i].Text == tt.match {
+					found = &matches[i]
+				}
+			}
+			if found == nil {
+				t.Fatalf("FindAll(%q) did not find %q", tt.text, tt.match)
+			}
+			if !slices.Contains(found.Signals, tt.signal) {
+				t.Errorf("FindAll(%q) signals for %q = %v, want %s", tt.text, tt.match, found.Signals, tt.signal)
+			}
+		})
+	}
+}
+
+func TestNoURLContext(t *testing.T) {
+	for _, tt := range []struct{ text, match string }{
+		{"Read main.go, setup.py", "setup.py"},
+		{"Visit the docs in example.com", "example.com"},
+		{"Meet me at example.com", "example.com"},
+		{"Copied from example.com", "example.com"},
+	} {
+		for _, m := range FindAll(tt.text) {
+			if m.Text == tt.match && slices.Contains(m.Signals, SignalURLContext) {
+				t.Errorf("FindAll(%q) signals for %q = %v, want no %s", tt.text, tt.match, m.Signals, SignalURLContext)
+			}
+		}
+	}
+}
+
+func TestMinConfidence(t *testing.T) {
+	text := "Visit https://example.com and www.example.org, then run setup.py and upgrade to version 1.2.3.4 or ASP.NET"
+
+	all := ExtractAll(text)
+	expectedAll := []string{"https://example.com", "www.example.org", "setup.py", "1.2.3.4", "ASP.NET"}
+	if !slices.Equal(all, expectedAll) {
+		t.Errorf("ExtractAll() = %v, want %v", all, expectedAll)
+	}
+
+	result := NewExtractor(WithMinConfidence(0.5)).ExtractAll(text)
+	expected := []string{"https://example.com", "www.example.org"}
+	if !slices.Equal(result, expected) {
+		t.Errorf("Extractor.ExtractAll() with minimum confidence = %v, want %v", result, expected)
+	}
+}
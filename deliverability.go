@@ -0,0 +1,196 @@
+package urlverify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MailResolver is a Resolver that also looks up the records MailCheck needs.
+// NewNetMailResolver and FakeResolver implement it.
+type MailResolver interface {
+	Resolver
+	// LookupMX returns the MX records of domain.
+	LookupMX(ctx context.Context, domain string) ([]*net.MX, error)
+	// LookupTXT returns the TXT records of name.
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// MailStatus tells whether a domain can receive mail.
+type MailStatus int
+
+const (
+	MailMX         MailStatus = iota // The domain has MX records
+	MailImplicitMX                   // No MX records, mail goes to the A or AAAA records (RFC 5321 5.1)
+	MailNullMX                       // The domain declares it accepts no mail with a null MX (RFC 7505)
+	MailNoRecords                    // The domain exists but has neither MX nor address records
+	MailNXDomain                     // The domain doesn't exist
+	MailInvalid                      // The domain isn't a valid domain name
+	MailTimeout                      // A lookup timed out
+	MailError                        // A lookup failed, e.g. SERVFAIL
+)
+
+func (s MailStatus) String() string {
+	switch s {
+	case MailMX:
+		return "MX"
+	case MailImplicitMX:
+		return "Implicit MX"
+	case MailNullMX:
+		return "Null MX"
+	case MailNoRecords:
+		return "No Records"
+	case MailNXDomain:
+		return "NXDOMAIN"
+	case MailInvalid:
+		return "Invalid Domain"
+	case MailTimeout:
+		return "Timeout"
+	case MailError:
+		return "Error"
+	default:
+		return "Unknown"
+	}
+}
+
+// MailResult is the outcome of checking whether a domain can receive mail.
+type MailResult struct {
+	Domain string     // The ASCII domain checked
+	Status MailStatus // Whether the domain can receive mail
+	MX     []*net.MX  // MX records ordered by preference, or the implicit MX
+	SPF    string     // The SPF record ("v=spf1 ..."), empty if there is none
+	DMARC  string     // The DMARC record at _dmarc.<domain>, empty if there is none
+	Err    error      // The lookup error for MailTimeout and MailError
+}
+
+// Deliverable reports whether mail to the domain can be delivered, through MX
+// records or the implicit MX.
+func (r MailResult) Deliverable() bool {
+	return r.Status == MailMX || r.Status == MailImplicitMX
+}
+
+// MailCheck checks whether domains can receive mail through a MailResolver.
+// A MailCheck is safe for concurrent use.
+type MailCheck struct {
+	resolver MailResolver
+	timeout  time.Duration
+}
+
+// MailOption configures a MailCheck.
+type MailOption func(*MailCheck)
+
+// WithMailTimeout sets how long a whole check may take, 10 seconds by default.
+func WithMailTimeout(d time.Duration) MailOption {
+	return func(c *MailCheck) {
+		c.timeout = d
+	}
+}
+
+// NewMailCheck creates a MailCheck looking up records through r.
+func NewMailCheck(r MailResolver, opts ...MailOption) *MailCheck {
+	c := &MailCheck{resolver: r, timeout: 10 * time.Second}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Check checks whether a domain, or the domain of an email address, can receive mail.
+// MX records are looked up first; a domain without any falls back to its A and AAAA
+// records as RFC 5321 requires. SPF and DMARC records are reported for domains that
+// exist.
+func (c *MailCheck) Check(ctx context.Context, domain string) MailResult {
+	if i := strings.LastIndexByte(domain, '@'); i >= 0 {
+		domain = domain[i+1:]
+	}
+	result := ValidateDomain(domain)
+	if !result.Valid || result.ASCIIHost == "" || result.Port != 0 || result.URL.Path != "" || result.URL.RawQuery != "" {
+		return MailResult{Domain: domain, Status: MailInvalid}
+	}
+	r := MailResult{Domain: result.ASCIIHost}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	mx, mxErr := c.resolver.LookupMX(ctx, r.Domain)
+	if mxErr != nil && !errors.Is(mxErr, ErrNXDomain) {
+		r.Status, r.Err = mailStatus(mxErr), mxErr
+		return r
+	}
+
+	switch {
+	case len(mx) > 0 && len(sortMX(mx)) == 0:
+		r.Status = MailNullMX
+	case len(mx) > 0:
+		r.Status, r.MX = MailMX, sortMX(mx)
+	default:
+		// No MX records, the domain itself is the implicit MX with preference 0
+		addrs, err := c.resolver.LookupHost(ctx, r.Domain)
+		switch {
+		case errors.Is(err, ErrNXDomain) && errors.Is(mxErr, ErrNXDomain):
+			r.Status = MailNXDomain
+			return r
+		case errors.Is(err, ErrNXDomain), err == nil && len(addrs) == 0:
+			r.Status = MailNoRecords
+		case err != nil:
+			r.Status, r.Err = mailStatus(err), err
+			return r
+		default:
+			r.Status, r.MX = MailImplicitMX, []*net.MX{{Host: r.Domain + ".", Pref: 0}}
+		}
+	}
+
+	r.SPF = c.lookupPolicy(ctx, r.Domain, "v=spf1")
+	r.DMARC = c.lookupPolicy(ctx, "_dmarc."+r.Domain, "v=DMARC1")
+	return r
+}
+
+// lookupPolicy returns the TXT record of name starting with the version tag, such
+// as "v=spf1". Lookup failures count as no record.
+func (c *MailCheck) lookupPolicy(ctx context.Context, name, version string) string {
+	txt, err := c.resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return ""
+	}
+	for _, record := range txt {
+		tag := strings.TrimSpace(record)
+		if i := strings.IndexAny(tag, " ;"); i >= 0 {
+			tag = tag[:i]
+		}
+		if strings.EqualFold(tag, version) {
+			return record
+		}
+	}
+	return ""
+}
+
+// isNullMX reports whether mx is the null MX of RFC 7505, "0 .".
+func isNullMX(mx *net.MX) bool {
+	return mx.Pref == 0 && (mx.Host == "." || mx.Host == "")
+}
+
+// sortMX orders MX records by preference, dropping null MX records; next to other
+// records they are invalid and ignored.
+func sortMX(mx []*net.MX) []*net.MX {
+	sorted := make([]*net.MX, 0, len(mx))
+	for _, r := range mx {
+		if !isNullMX(r) {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Pref < sorted[j].Pref
+	})
+	return sorted
+}
+
+// mailStatus classifies a lookup error, see resolveStatus.
+func mailStatus(err error) MailStatus {
+	if resolveStatus(err) == ResolveTimeout {
+		return MailTimeout
+	}
+	return MailError
+}
@@ -0,0 +1,105 @@
+package urlverify
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// newMailResolver returns fixtures for the common mail setups.
+func newMailResolver() *FakeResolver {
+	r := NewFakeResolver()
+
+	// Regular mail domain with two exchangers, SPF and DMARC
+	r.AddMX("example.com", "mx2.example.com.", 20)
+	r.AddMX("example.com", "mx1.example.com.", 10)
+	r.AddHost("example.com", netip.MustParseAddr("192.0.2.1"))
+	r.AddTXT("example.com", "google-site-verification=abc")
+	r.AddTXT("example.com", "v=spf1 include:_spf.example.net -all")
+	r.AddTXT("_dmarc.example.com", "v=DMARC1;p=reject")
+
+	// No MX, mail goes to the address record
+	r.AddHost("implicit.example.org", netip.MustParseAddr("2001:db8::25"))
+
+	// Null MX, no mail at all
+	r.AddMX("nomail.example.net", ".", 0)
+	r.AddHost("nomail.example.net", netip.MustParseAddr("192.0.2.2"))
+	r.AddTXT("nomail.example.net", "v=spf1 -all")
+
+	// Exists with TXT records only
+	r.AddTXT("txt-only.example.com", "hello")
+
+	r.SetError("broken.example.com", errors.New("server misbehaving"))
+	return r
+}
+
+func TestMailCheck(t *testing.T) {
+	c := NewMailCheck(newMailResolver())
+	ctx := context.Background()
+
+	tests := []struct {
+		input       string
+		domain      string
+		status      MailStatus
+		deliverable bool
+		mx          []string
+		spf, dmarc  bool
+	}{
+		{"example.com", "example.com", MailMX, true, []string{"mx1.example.com.", "mx2.example.com."}, true, true},
+		{"Someone@EXAMPLE.com", "example.com", MailMX, true, []string{"mx1.example.com.", "mx2.example.com."}, true, true},
+		{"implicit.example.org", "implicit.example.org", MailImplicitMX, true, []string{"implicit.example.org."}, false, false},
+		{"nomail.example.net", "nomail.example.net", MailNullMX, false, nil, true, false},
+		{"txt-only.example.com", "txt-only.example.com", MailNoRecords, false, nil, false, false},
+		{"missing.example.com", "missing.example.com", MailNXDomain, false, nil, false, false},
+		{"broken.example.com", "broken.example.com", MailError, false, nil, false, false},
+		{"user@not_a_domain", "not_a_domain", MailInvalid, false, nil, false, false},
+		{"https://example.com/path", "https://example.com/path", MailInvalid, false, nil, false, false},
+	}
+
+	for _, tt := range tests {
+		r := c.Check(ctx, tt.input)
+		if r.Domain != tt.domain || r.Status != tt.status || r.Deliverable() != tt.deliverable {
+			t.Errorf("Check(%q) = %q %v deliverable %v, want %q %v", tt.input, r.Domain, r.Status, r.Deliverable(), tt.domain, tt.status)
+		}
+		var mx []string
+		for _, m := range r.MX {
+			mx = append(mx, m.Host)
+		}
+		if len(mx) != len(tt.mx) || len(mx) > 0 && mx[0] != tt.mx[0] {
+			t.Errorf("Check(%q).MX = %v, want %v", tt.input, mx, tt.mx)
+		}
+		if (r.SPF != "") != tt.spf || (r.DMARC != "") != tt.dmarc {
+			t.Errorf("Check(%q) SPF = %q, DMARC = %q", tt.input, r.SPF, r.DMARC)
+		}
+	}
+
+	if r := c.Check(ctx, "example.com"); r.SPF != "v=spf1 include:_spf.example.net -all" || r.DMARC != "v=DMARC1;p=reject" {
+		t.Errorf("policies = %q, %q", r.SPF, r.DMARC)
+	}
+}
+
+func TestMailCheckNullMXWithOthers(t *testing.T) {
+	r := NewFakeResolver()
+	r.AddMX("mixed.example.com", ".", 0)
+	r.AddMX("mixed.example.com", "mx.example.com.", 10)
+
+	result := NewMailCheck(r).Check(context.Background(), "mixed.example.com")
+	if result.Status != MailMX || len(result.MX) != 1 || result.MX[0].Host != "mx.example.com." {
+		t.Errorf("Check() = %v %v, want the null MX ignored", result.Status, result.MX)
+	}
+}
+
+func TestMailCheckTimeout(t *testing.T) {
+	r := newMailResolver()
+	r.Delay = time.Second
+
+	result := NewMailCheck(r, WithMailTimeout(10*time.Millisecond)).Check(context.Background(), "example.com")
+	if result.Status != MailTimeout || result.Err == nil {
+		t.Errorf("Check() = %v %v, want timeout", result.Status, result.Err)
+	}
+	if MailNullMX.String() != "Null MX" {
+		t.Errorf("String() = %q", MailNullMX)
+	}
+}
@@ -0,0 +1,220 @@
+package urlverify
+
+//go:generate go run ./internal/ngramgen
+
+import (
+	"bufio"
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DGAScore rates how likely a domain is algorithmically generated, as malware does
+// for its command and control servers, from the label left of the public suffix.
+// "xjqvzkpt3lmw.com" scores high, "stackoverflow.com" low.
+type DGAScore struct {
+	Label string // The label scored, e.g. "xjqvzkpt3lmw" for xjqvzkpt3lmw.com
+
+	// Score combines the features below into a value from 0, a natural name, to 1,
+	// almost certainly generated. Labels shorter than dgaMinLength are damped towards 0.
+	Score float64
+
+	Entropy        float64 // Shannon entropy of the label's characters in bits
+	ConsonantRatio float64 // Share of consonants among the letters
+	ConsonantRun   int     // Longest run of consecutive consonants
+	DigitRatio     float64 // Share of digits among all characters
+	DigitSwitches  int     // Changes between letters and digits, as in "a1b2"
+	BigramLogProb  float64 // Mean log10 probability of the letter bigrams under the model
+	TrigramLogProb float64 // Mean log10 probability of the letter trigrams under the model
+}
+
+// WithDGAScoring makes the Validator score valid domains for being algorithmically
+// generated in ValidationResult.DGA, see ScoreDGA.
+func WithDGAScoring() ValidatorOption {
+	return func(v *Validator) {
+		v.dgaScoring = true
+	}
+}
+
+// dgaMinLength is the label length from which scores count fully. Short labels
+// carry too little to tell, and short generated names are rare.
+const dgaMinLength = 8
+
+// dgaWeights weigh the risk of each feature, from 0 to 1, into the score.
+var dgaWeights = struct {
+	trigram, bigram, consonants, digits, entropy float64
+}{0.45, 0.15, 0.15, 0.15, 0.1}
+
+//go:embed data/ngrams.txt
+var ngramData string
+
+// ngramModel holds the letter trigram counts, indexed by symbol: '^', 'a' to 'z', '$'.
+type ngramModel struct {
+	trigrams [28][28][28]float64
+	bigrams  [28][28]float64 // Counts of the symbol pairs of the training words
+	unigrams [28]float64     // Counts of the symbols followed by another
+}
+
+var dgaModel = sync.OnceValue(loadNgramModel)
+
+func loadNgramModel() *ngramModel {
+	m := &ngramModel{}
+	scanner := bufio.NewScanner(strings.NewReader(ngramData))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		gram, count, ok := strings.Cut(line, " ")
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil || len(gram) != 3 {
+			panic("urlverify: invalid n-gram model line " + strconv.Quote(line))
+		}
+		a, b, c := ngramSymbol(gram[0]), ngramSymbol(gram[1]), ngramSymbol(gram[2])
+		m.trigrams[a][b][c] += float64(n)
+		m.bigrams[a][b] += float64(n)
+		m.unigrams[a] += float64(n)
+		if c == ngramSymbol('$') {
+			// The last pair of a word only ends a trigram
+			m.bigrams[b][c] += float64(n)
+			m.unigrams[b] += float64(n)
+		}
+	}
+	return m
+}
+
+func ngramSymbol(c byte) int {
+	switch {
+	case c == '^':
+		return 0
+	case c == '$':
+		return 27
+	default:
+		return int(c-'a') + 1
+	}
+}
+
+// ScoreDGA scores a URL or domain for being algorithmically generated. Invalid input,
+// IP addresses and internationalized labels, which the English model can't judge,
+// score 0.
+func ScoreDGA(raw string) DGAScore {
+	result := ValidateDomain(raw)
+	if !result.Valid || result.Type == URLTypeIP {
+		return DGAScore{}
+	}
+	return scoreLabel(registrableLabel(result.ASCIIHost, result.TLD))
+}
+
+// registrableLabel returns the label of host left of its public suffix.
+func registrableLabel(host, eTLD string) string {
+	rest, ok := strings.CutSuffix(host, "."+eTLD)
+	if !ok {
+		return ""
+	}
+	return rest[strings.LastIndexByte(rest, '.')+1:]
+}
+
+func scoreLabel(label string) DGAScore {
+	s := DGAScore{Label: label}
+	if label == "" || strings.HasPrefix(label, "xn--") {
+		return s
+	}
+
+	var (
+		chars                    = make(map[rune]int)
+		letters, consonants, run int
+		digits                   int
+		prevDigit, prevSet       bool
+	)
+	for _, r := range label {
+		chars[r]++
+		isDigit := r >= '0' && r <= '9'
+		if prevSet && isDigit != prevDigit && r != '-' {
+			s.DigitSwitches++
+		}
+		if r != '-' {
+			prevDigit, prevSet = isDigit, true
+		}
+
+		switch {
+		case isDigit:
+			digits++
+			run = 0
+		case r >= 'a' && r <= 'z':
+			letters++
+			if strings.ContainsRune("aeiouy", r) {
+				run = 0
+			} else {
+				consonants++
+				run++
+				s.ConsonantRun = max(s.ConsonantRun, run)
+			}
+		default:
+			run = 0
+		}
+	}
+
+	n := float64(len(label))
+	for _, count := range chars {
+		p := float64(count) / n
+		s.Entropy -= p * math.Log2(p)
+	}
+	if letters > 0 {
+		s.ConsonantRatio = float64(consonants) / float64(letters)
+	}
+	s.DigitRatio = float64(digits) / n
+	s.BigramLogProb, s.TrigramLogProb = dgaModel().logProbs(label)
+
+	s.Score = s.combine(len(label))
+	return s
+}
+
+// logProbs returns the mean log10 probabilities of the bigrams and trigrams of the
+// letter runs of label, each padded with '^' and '$', with add-one smoothing.
+func (m *ngramModel) logProbs(label string) (bigram, trigram float64) {
+	var bigrams, trigrams int
+	for _, run := range strings.FieldsFunc(label, func(r rune) bool { return r < 'a' || r > 'z' }) {
+		padded := "^" + run + "$"
+		for i := 0; i+2 <= len(padded); i++ {
+			a, b := ngramSymbol(padded[i]), ngramSymbol(padded[i+1])
+			bigram += math.Log10((m.bigrams[a][b] + 1) / (m.unigrams[a] + 28))
+			bigrams++
+			if i+3 <= len(padded) {
+				c := ngramSymbol(padded[i+2])
+				trigram += math.Log10((m.trigrams[a][b][c] + 1) / (m.bigrams[a][b] + 28))
+				trigrams++
+			}
+		}
+	}
+	if bigrams > 0 {
+		bigram /= float64(bigrams)
+	}
+	if trigrams > 0 {
+		trigram /= float64(trigrams)
+	}
+	return bigram, trigram
+}
+
+// combine weighs the features into the score. The ranges mapped to risks from 0 to 1
+// are tuned to separate popular sites from known DGA output.
+func (s DGAScore) combine(length int) float64 {
+	risk := func(v, low, high float64) float64 {
+		return math.Min(math.Max((v-low)/(high-low), 0), 1)
+	}
+
+	// Labels without letters have no n-grams, only their digits count
+	trigram := risk(-s.TrigramLogProb, 1.0, 1.7)
+	bigram := risk(-s.BigramLogProb, 1.8, 2.6)
+	consonants := math.Max(risk(s.ConsonantRatio, 0.65, 0.9), risk(float64(s.ConsonantRun), 3, 6))
+	digits := math.Max(risk(s.DigitRatio, 0.1, 0.5), risk(float64(s.DigitSwitches), 1, 4))
+	entropy := risk(s.Entropy/math.Log2(float64(length)+1), 0.75, 0.95)
+
+	score := dgaWeights.trigram*trigram + dgaWeights.bigram*bigram + dgaWeights.consonants*consonants +
+		dgaWeights.digits*digits + dgaWeights.entropy*entropy
+	if length < dgaMinLength {
+		score *= float64(length) / dgaMinLength
+	}
+	return math.Round(score*1000) / 1000
+}
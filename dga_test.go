@@ -0,0 +1,77 @@
+package urlverify
+
+import "testing"
+
+func TestScoreDGA(t *testing.T) {
+	natural := []string{
+		"google.com", "facebook.com", "https://stackoverflow.com/questions", "en.wikipedia.org",
+		"bestbuy.com", "www.nytimes.com", "kubernetes.io", "github.com", "microsoft.com",
+		"bbc.co.uk", "theguardian.com", "zillow.com", "my-site-2024.com", "qq.com",
+	}
+	generated := []string{
+		"xjqvzkpt3lmw.com", "qwkfjhzbxv.net", "a1b2c3d4e5f6.com", "http://lxnmrpqtsvwz.org/gate.php",
+		"h3k9d2l5p8.com", "cxzyqgwerdfh.ru", "pmfgqkbvqtdqg.com", "cdn.ihjmbfxvqpdi.ru",
+	}
+	for _, domain := range natural {
+		if s := ScoreDGA(domain); s.Score >= 0.5 {
+			t.Errorf("ScoreDGA(%q) = %.3f, want < 0.5 (%+v)", domain, s.Score, s)
+		}
+	}
+	for _, domain := range generated {
+		if s := ScoreDGA(domain); s.Score < 0.6 {
+			t.Errorf("ScoreDGA(%q) = %.3f, want >= 0.6 (%+v)", domain, s.Score, s)
+		}
+	}
+
+	s := ScoreDGA("https://cdn.xjqvzkpt3lmw.co.uk/a")
+	if s.Label != "xjqvzkpt3lmw" || s.ConsonantRatio != 1 || s.ConsonantRun != 8 || s.DigitSwitches != 2 {
+		t.Errorf("ScoreDGA(xjqvzkpt3lmw) features = %+v", s)
+	}
+	if s.BigramLogProb >= 0 || s.TrigramLogProb >= 0 || s.Entropy <= 3 {
+		t.Errorf("ScoreDGA(xjqvzkpt3lmw) model = %+v", s)
+	}
+
+	for _, skipped := range []string{"http://192.0.2.1/", "xn--bcher-kva.de", "not a domain"} {
+		if s := ScoreDGA(skipped); s.Score != 0 {
+			t.Errorf("ScoreDGA(%q) = %+v, want 0", skipped, s)
+		}
+	}
+	// Short labels are damped
+	if short, long := ScoreDGA("xq.com"), ScoreDGA("xqzkvbnw.com"); short.Score >= long.Score/2 {
+		t.Errorf("short label score %.3f, long %.3f", short.Score, long.Score)
+	}
+}
+
+func TestValidatorDGAScoring(t *testing.T) {
+	if r := ValidateDomain("xjqvzkpt3lmw.com"); r.DGA != nil {
+		t.Errorf("DGA scored without WithDGAScoring: %+v", r.DGA)
+	}
+
+	v := NewValidator(WithDGAScoring())
+	r := v.ValidateDomain("https://xjqvzkpt3lmw.com/")
+	if r.DGA == nil || r.DGA.Score < 0.6 {
+		t.Errorf("ValidateDomain(generated).DGA = %+v", r.DGA)
+	}
+	if r := v.ValidateDomain("https://www.google.com/"); r.DGA == nil || r.DGA.Label != "google" || r.DGA.Score >= 0.5 {
+		t.Errorf("ValidateDomain(google).DGA = %+v", r.DGA)
+	}
+	if r := v.ValidateDomain("http://192.0.2.1/"); r.DGA != nil {
+		t.Errorf("ValidateDomain(IP).DGA = %+v", r.DGA)
+	}
+}
+
+func TestNgramModelBigrams(t *testing.T) {
+	m := dgaModel()
+	for a := range m.bigrams {
+		var sum float64
+		for _, n := range m.bigrams[a] {
+			sum += n
+		}
+		if sum != m.unigrams[a] {
+			t.Errorf("bigrams after symbol %d sum to %v, want %v", a, sum, m.unigrams[a])
+		}
+	}
+	if n := m.bigrams[ngramSymbol('e')][ngramSymbol('$')]; n == 0 {
+		t.Error("no bigram counts for words ending in 'e'")
+	}
+}
@@ -0,0 +1,127 @@
+package urlverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DocumentDecoder decodes a serialized document into generic Go values: maps, slices,
+// strings, numbers, booleans and nil, as json.Unmarshal does into an interface{}.
+// It lets ExtractDocument handle formats such as YAML or TOML without this package
+// depending on their parsers.
+type DocumentDecoder func(data []byte) (any, error)
+
+// DocumentMatch represents a valid URL or domain found in a structured document.
+// Start and End are offsets into the string value the match came from.
+type DocumentMatch struct {
+	Match
+	Path string // JSON Pointer (RFC 6901) to the value, or to the member for keys
+	Key  bool   // Whether the match was found in an object key rather than a value
+}
+
+// ExtractJSON decodes a JSON document and extracts valid URLs and domains from all of its
+// string values and object keys. Members are visited in sorted key order.
+func ExtractJSON(data []byte) ([]DocumentMatch, error) {
+	return ExtractDocument(data, DecodeJSON)
+}
+
+// DecodeJSON is a DocumentDecoder for JSON.
+func DecodeJSON(data []byte) (any, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ExtractDocument decodes data with decode and extracts valid URLs and domains
+// from the resulting values as ExtractValue does.
+func ExtractDocument(data []byte, decode DocumentDecoder) ([]DocumentMatch, error) {
+	v, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return ExtractValue(v), nil
+}
+
+// ExtractValue walks already decoded generic values and extracts valid URLs and domains
+// from all strings. Typed maps and slices such as map[string]string or []string are
+// walked too, and so are maps with non-string keys, as produced by some YAML decoders;
+// their keys appear in paths formatted with fmt, and only string keys are searched.
+func ExtractValue(v any) []DocumentMatch {
+	var matches []DocumentMatch
+	walkValue(reflect.ValueOf(v), "", &matches)
+	return matches
+}
+
+func walkValue(v reflect.Value, path string, matches *[]DocumentMatch) {
+	switch v.Kind() {
+	case reflect.String:
+		appendDocumentMatches(matches, v.String(), path, false)
+
+	case reflect.Interface, reflect.Pointer:
+		if !v.IsNil() {
+			walkValue(v.Elem(), path, matches)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			walkValue(v.Index(i), path+"/"+strconv.Itoa(i), matches)
+		}
+
+	case reflect.Map:
+		type member struct {
+			key, value reflect.Value
+			token      string
+		}
+		members := make([]member, 0, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			key := iter.Key()
+			token := fmt.Sprint(key.Interface())
+			if key.Kind() == reflect.Interface {
+				key = key.Elem()
+			}
+			members = append(members, member{key, iter.Value(), token})
+		}
+		// Keys that format the same, such as 1 and "1", are ordered by type
+		sort.Slice(members, func(i, j int) bool {
+			a, b := members[i], members[j]
+			if a.token != b.token {
+				return a.token < b.token
+			}
+			return typeName(a.key) < typeName(b.key)
+		})
+		for _, m := range members {
+			memberPath := path + "/" + escapePointer(m.token)
+			if m.key.Kind() == reflect.String {
+				appendDocumentMatches(matches, m.token, memberPath, true)
+			}
+			walkValue(m.value, memberPath, matches)
+		}
+	}
+}
+
+// typeName returns the name of the type of v, empty for the nil interface.
+func typeName(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	return v.Type().String()
+}
+
+func appendDocumentMatches(matches *[]DocumentMatch, s, path string, key bool) {
+	for _, m := range FindAll(s) {
+		*matches = append(*matches, DocumentMatch{Match: m, Path: path, Key: key})
+	}
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// escapePointer escapes a reference token for use in a JSON Pointer.
+func escapePointer(token string) string {
+	return pointerEscaper.Replace(token)
+}
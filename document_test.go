@@ -0,0 +1,130 @@
+package urlverify
+
+import (
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	data := []byte(`{
+		"homepage": "https:\/\/example.com\/about",
+		"links": ["see github.com", {"href": "http://[2001:db8::1]:443/page"}, 42, null],
+		"a/b~c": "foo.dyndns.org",
+		"https://key.example.org": true,
+		"note": "nothing here"
+	}`)
+
+	expected := []struct {
+		text string
+		path string
+		key  bool
+	}{
+		{"foo.dyndns.org", "/a~1b~0c", false},
+		{"https://example.com/about", "/homepage", false},
+		{"https://key.example.org", "/https:~1~1key.example.org", true},
+		{"github.com", "/links/0", false},
+		{"http://[2001:db8::1]:443/page", "/links/1/href", false},
+	}
+
+	result, err := ExtractJSON(data)
+	if err != nil {
+		t.Fatalf("ExtractJSON() error = %v", err)
+	}
+
+	if len(result) != len(expected) {
+		t.Errorf("ExtractJSON() returned %d matches, want %d", len(result), len(expected))
+		for _, m := range result {
+			t.Logf("Got: %q at %s", m.Text, m.Path)
+		}
+		return
+	}
+
+	for i, want := range expected {
+		got := result[i]
+		if got.Text != want.text || got.Path != want.path || got.Key != want.key {
+			t.Errorf("ExtractJSON() result[%d] = {%q %q %v}, want {%q %q %v}", i, got.Text, got.Path, got.Key, want.text, want.path, want.key)
+		}
+	}
+
+	if _, err := ExtractJSON([]byte(`{"broken": `)); err == nil {
+		t.Errorf("ExtractJSON() with malformed input returned no error")
+	}
+}
+
+func TestExtractValueNonStringKeys(t *testing.T) {
+	// The shape produced by YAML decoders that don't force string keys
+	v := map[any]any{
+		"servers": []any{
+			map[any]any{"url": "https://api.example.com/v1"},
+		},
+		8080: "backup.example.org",
+	}
+
+	expected := []struct {
+		text string
+		path string
+	}{
+		{"backup.example.org", "/8080"},
+		{"https://api.example.com/v1", "/servers/0/url"},
+	}
+
+	result := ExtractValue(v)
+
+	if len(result) != len(expected) {
+		t.Errorf("ExtractValue() returned %d matches, want %d", len(result), len(expected))
+		return
+	}
+
+	for i, want := range expected {
+		if result[i].Text != want.text || result[i].Path != want.path {
+			t.Errorf("ExtractValue() result[%d] = {%q %q}, want {%q %q}", i, result[i].Text, result[i].Path, want.text, want.path)
+		}
+	}
+}
+
+func TestExtractValueKeyCollisions(t *testing.T) {
+	v := map[any]any{
+		1:   "one.example.com",
+		"1": "string-one.example.com",
+	}
+
+	result := ExtractValue(v)
+	expected := []string{"one.example.com", "string-one.example.com"}
+	if len(result) != len(expected) {
+		t.Fatalf("ExtractValue() returned %d matches, want %d: %v", len(result), len(expected), result)
+	}
+	for i, want := range expected {
+		if result[i].Text != want || result[i].Path != "/1" {
+			t.Errorf("ExtractValue() result[%d] = {%q %q}, want {%q %q}", i, result[i].Text, result[i].Path, want, "/1")
+		}
+	}
+}
+
+func TestExtractValueTypedContainers(t *testing.T) {
+	v := map[string]any{
+		"hosts":  []string{"a.example.com", "b.example.com"},
+		"labels": map[string]string{"home": "https://example.org/", "www.example.net": "mirror"},
+		"ports":  map[int][]string{443: {"secure.example.com"}},
+	}
+
+	expected := []struct {
+		text string
+		path string
+		key  bool
+	}{
+		{"a.example.com", "/hosts/0", false},
+		{"b.example.com", "/hosts/1", false},
+		{"https://example.org/", "/labels/home", false},
+		{"www.example.net", "/labels/www.example.net", true},
+		{"secure.example.com", "/ports/443/0", false},
+	}
+
+	result := ExtractValue(v)
+	if len(result) != len(expected) {
+		t.Fatalf("ExtractValue() returned %d matches, want %d: %v", len(result), len(expected), result)
+	}
+	for i, want := range expected {
+		if result[i].Text != want.text || result[i].Path != want.path || result[i].Key != want.key {
+			t.Errorf("ExtractValue() result[%d] = {%q %q %v}, want {%q %q %v}", i, result[i].Text, result[i].Path, result[i].Key, want.text, want.path, want.key)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package urlverify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DomainSet is a set of domain patterns for allowlists and blocklists, answering
+// whether a host or any of its parents is listed. Entries are stored in a label trie
+// keyed from the TLD down, with chains of single-child labels merged into one node,
+// so millions of entries sharing TLDs and parents take little memory.
+//
+// Patterns are written as
+//
+//	example.com     the domain itself only
+//	*.example.com   subdomains of example.com, but not example.com
+//	.example.com    example.com and all its subdomains
+//
+// Hosts and patterns are normalized like NormalizeURI, so internationalized names match
+// their Punycode form. The zero value is an empty set; lookups may run concurrently,
+// but not alongside Add.
+type DomainSet struct {
+	root domainNode
+	size int
+}
+
+// domainNode is a node of the label trie. edge holds the labels leading to it from
+// its parent, nearest the root first and joined by dots, e.g. "example.www" for the
+// node of www.example.com below the node of com.
+type domainNode struct {
+	edge     string
+	flags    domainFlags
+	children []*domainNode // Ordered by the first label of their edge
+}
+
+type domainFlags uint8
+
+const (
+	flagExact      domainFlags = 1 << iota // The name itself
+	flagWildcard                           // Names below it
+	flagSubdomains                         // The name and names below it
+)
+
+// pattern returns the pattern for flag on name, as written when added.
+func (f domainFlags) pattern(name string) string {
+	switch f {
+	case flagWildcard:
+		return "*." + name
+	case flagSubdomains:
+		return "." + name
+	default:
+		return name
+	}
+}
+
+// NewDomainSet creates a DomainSet holding the given patterns.
+func NewDomainSet(patterns ...string) (*DomainSet, error) {
+	s := &DomainSet{}
+	for _, p := range patterns {
+		if err := s.Add(p); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add adds a pattern to the set, see DomainSet for the syntax.
+func (s *DomainSet) Add(pattern string) error {
+	flag := flagExact
+	name := pattern
+	if rest, ok := strings.CutPrefix(name, "*."); ok {
+		flag, name = flagWildcard, rest
+	} else if rest, ok := strings.CutPrefix(name, "."); ok {
+		flag, name = flagSubdomains, rest
+	}
+
+	name, err := normalizeSetName(name)
+	if err != nil {
+		return fmt.Errorf("domain set pattern %q: %w", pattern, err)
+	}
+	s.insert(name, flag)
+	return nil
+}
+
+// AddRegistrable adds the registrable domain of host, its public suffix plus one label,
+// with all its subdomains. Adding "www.example.co.uk" covers example.co.uk and every
+// name below it.
+func (s *DomainSet) AddRegistrable(host string) error {
+	name, err := normalizeSetName(host)
+	if err == nil {
+		name, err = publicsuffix.EffectiveTLDPlusOne(name)
+	}
+	if err != nil {
+		return fmt.Errorf("domain set host %q: %w", host, err)
+	}
+	s.insert(name, flagSubdomains)
+	return nil
+}
+
+// Len returns the number of patterns in the set.
+func (s *DomainSet) Len() int {
+	return s.size
+}
+
+// Contains reports whether host matches a pattern in the set.
+func (s *DomainSet) Contains(host string) bool {
+	_, ok := s.Lookup(host)
+	return ok
+}
+
+// Lookup returns the most specific pattern in the set that host matches, normalized
+// to its ASCII form.
+func (s *DomainSet) Lookup(host string) (pattern string, ok bool) {
+	patterns := s.lookupAll(host)
+	if len(patterns) == 0 {
+		return "", false
+	}
+	return patterns[0], true
+}
+
+// lookupAll returns all patterns in the set that host matches, most specific first.
+func (s *DomainSet) lookupAll(host string) []string {
+	name, err := normalizeSetName(host)
+	if err != nil || s.size == 0 {
+		return nil
+	}
+
+	var (
+		node     = &s.root
+		rest     = name // Labels not consumed yet, the last one is next
+		patterns []string
+	)
+	for rest != "" {
+		node = node.child(lastLabel(rest))
+		if node == nil {
+			break
+		}
+		consumed, ok := consumeEdge(node.edge, rest)
+		if !ok {
+			break
+		}
+		rest = rest[:len(rest)-consumed]
+		suffix := strings.TrimPrefix(name[len(rest):], ".")
+
+		// Prepended, deeper nodes and exact names are more specific
+		if node.flags&flagSubdomains != 0 {
+			patterns = append([]string{flagSubdomains.pattern(suffix)}, patterns...)
+		}
+		if rest != "" && node.flags&flagWildcard != 0 {
+			patterns = append([]string{flagWildcard.pattern(suffix)}, patterns...)
+		}
+		if rest == "" && node.flags&flagExact != 0 {
+			patterns = append([]string{flagExact.pattern(suffix)}, patterns...)
+		}
+	}
+
+	return patterns
+}
+
+// insert adds name to the trie with flag.
+func (s *DomainSet) insert(name string, flag domainFlags) {
+	labels := strings.Split(name, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+
+	node := &s.root
+	for len(labels) > 0 {
+		i, child := node.search(labels[0])
+		if child == nil {
+			child = &domainNode{edge: strings.Join(labels, ".")}
+			node.children = append(node.children, nil)
+			copy(node.children[i+1:], node.children[i:])
+			node.children[i] = child
+			node = child
+			break
+		}
+
+		edge := strings.Split(child.edge, ".")
+		common := 0
+		for common < len(edge) && common < len(labels) && edge[common] == labels[common] {
+			common++
+		}
+		if common < len(edge) {
+			// Split the edge, the new node keeps the child's position as it starts
+			// with the same label
+			parent := &domainNode{edge: strings.Join(edge[:common], "."), children: []*domainNode{child}}
+			child.edge = strings.Join(edge[common:], ".")
+			node.children[i] = parent
+			child = parent
+		}
+		node = child
+		labels = labels[common:]
+	}
+
+	if node.flags&flag == 0 {
+		node.flags |= flag
+		s.size++
+	}
+}
+
+// search returns the position of the child whose edge starts with label, or the
+// position to insert it at and nil.
+func (n *domainNode) search(label string) (int, *domainNode) {
+	i := sort.Search(len(n.children), func(i int) bool {
+		return firstLabel(n.children[i].edge) >= label
+	})
+	if i < len(n.children) && firstLabel(n.children[i].edge) == label {
+		return i, n.children[i]
+	}
+	return i, nil
+}
+
+func (n *domainNode) child(label string) *domainNode {
+	_, c := n.search(label)
+	return c
+}
+
+// consumeEdge matches the labels of edge, nearest the root first, against the last
+// labels of name and returns the number of bytes of name they cover.
+func consumeEdge(edge, name string) (int, bool) {
+	end := len(name)
+	for edge != "" {
+		var label string
+		label, edge, _ = strings.Cut(edge, ".")
+		if end == 0 {
+			return 0, false
+		}
+		start := strings.LastIndexByte(name[:end], '.') + 1
+		if name[start:end] != label {
+			return 0, false
+		}
+		end = max(start-1, 0)
+	}
+	return len(name) - end, true
+}
+
+func firstLabel(edge string) string {
+	if i := strings.IndexByte(edge, '.'); i >= 0 {
+		return edge[:i]
+	}
+	return edge
+}
+
+func lastLabel(name string) string {
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+// normalizeSetName normalizes a host or pattern name for the DomainSet trie.
+func normalizeSetName(name string) (string, error) {
+	name, err := NormalizeURI(strings.TrimSuffix(name, "."))
+	if err != nil {
+		return "", err
+	}
+	if name == "" || strings.Contains(name, "..") || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("empty label")
+	}
+	return name, nil
+}
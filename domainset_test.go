@@ -0,0 +1,125 @@
+package urlverify
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDomainSetLookup(t *testing.T) {
+	set, err := NewDomainSet(
+		"example.com",
+		"*.wild.example.org",
+		".corp.example.net",
+		"deep.a.b.c.example.io",
+		"b.c.example.io",
+		"книга.рф",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := set.AddRegistrable("www.shop.example.co.uk"); err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 7 {
+		t.Errorf("Len() = %d, want 7", set.Len())
+	}
+
+	tests := []struct {
+		host    string
+		pattern string
+	}{
+		{"example.com", "example.com"},
+		{"EXAMPLE.COM.", "example.com"},
+		{"www.example.com", ""},
+		{"wild.example.org", ""},
+		{"a.wild.example.org", "*.wild.example.org"},
+		{"x.y.wild.example.org", "*.wild.example.org"},
+		{"corp.example.net", ".corp.example.net"},
+		{"mail.corp.example.net", ".corp.example.net"},
+		{"notcorp.example.net", ""},
+		{"deep.a.b.c.example.io", "deep.a.b.c.example.io"},
+		{"a.b.c.example.io", ""},
+		{"b.c.example.io", "b.c.example.io"},
+		{"c.example.io", ""},
+		{"книга.рф", "xn--80afohp.xn--p1ai"},
+		{"xn--80afohp.xn--p1ai", "xn--80afohp.xn--p1ai"},
+		{"api.example.co.uk", ".example.co.uk"},
+		{"example.co.uk", ".example.co.uk"},
+		{"co.uk", ""},
+		{"com", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		pattern, ok := set.Lookup(tt.host)
+		if pattern != tt.pattern || ok != (tt.pattern != "") {
+			t.Errorf("Lookup(%q) = %q, %v, want %q", tt.host, pattern, ok, tt.pattern)
+		}
+	}
+}
+
+func TestDomainSetMostSpecific(t *testing.T) {
+	set, _ := NewDomainSet(".example.com", "*.api.example.com", "v1.api.example.com")
+
+	tests := map[string]string{
+		"example.com":        ".example.com",
+		"api.example.com":    ".example.com",
+		"v2.api.example.com": "*.api.example.com",
+		"v1.api.example.com": "v1.api.example.com",
+	}
+	for host, want := range tests {
+		if got, _ := set.Lookup(host); got != want {
+			t.Errorf("Lookup(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestDomainSetAddErrors(t *testing.T) {
+	var set DomainSet
+	for _, p := range []string{"", "*.", "a..com", "..com"} {
+		if err := set.Add(p); err == nil {
+			t.Errorf("Add(%q) succeeded, want error", p)
+		}
+	}
+	if err := set.AddRegistrable("co.uk"); err == nil {
+		t.Error("AddRegistrable(co.uk) succeeded, want error")
+	}
+	if set.Len() != 0 || set.Contains("com") {
+		t.Errorf("failed additions changed the set")
+	}
+
+	// Adding a pattern twice counts once
+	set.Add("example.com")
+	set.Add("Example.com")
+	if set.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", set.Len())
+	}
+}
+
+func TestExtractorDomainSets(t *testing.T) {
+	text := "Links: https://docs.example.com/a http://tracker.ads.example.net/p example.org 192.0.2.1"
+
+	allow, _ := NewDomainSet(".example.com", "example.org")
+	got := NewExtractor(WithAllowList(allow)).ExtractAll(text)
+	if want := []string{"https://docs.example.com/a", "example.org"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("allow list = %q, want %q", got, want)
+	}
+
+	deny, _ := NewDomainSet("*.ads.example.net")
+	got = NewExtractor(WithDenyList(deny)).ExtractAll(text)
+	if want := []string{"https://docs.example.com/a", "example.org", "192.0.2.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("deny list = %q, want %q", got, want)
+	}
+}
+
+func BenchmarkDomainSetContains(b *testing.B) {
+	var set DomainSet
+	for i := 0; i < 100000; i++ {
+		set.Add(fmt.Sprintf(".host%d.example%d.com", i, i%100))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Contains("www.host4242.example42.com")
+	}
+}
@@ -0,0 +1,229 @@
+package urlverify
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// Encoding is how a URL embedded in a query parameter or fragment was written.
+type Encoding int
+
+const (
+	EncodingPlain   Encoding = iota // As is, e.g. "?next=https://example.com/"
+	EncodingPercent                 // Percent-encoded, possibly more than once, e.g. "?next=https%3A%2F%2Fexample.com%2F"
+	EncodingBase64                  // Base64 in the standard or URL alphabet, e.g. "?r=aHR0cHM6Ly9leGFtcGxlLmNvbS8="
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingPlain:
+		return "Plain"
+	case EncodingPercent:
+		return "Percent"
+	case EncodingBase64:
+		return "Base64"
+	default:
+		return "Unknown"
+	}
+}
+
+// EmbeddedURL is a URL found in a query parameter or the fragment of another URL.
+type EmbeddedURL struct {
+	Param    string           // Name of the parameter, empty if the whole fragment is the URL
+	Fragment bool             // Whether the URL was found in the fragment rather than the query
+	Encoding Encoding         // How the URL was encoded in the parameter
+	URL      string           // The decoded URL
+	Result   ValidationResult // Validation result for the URL
+
+	// CrossDomain reports whether the URL leads to another registrable domain than
+	// the URL it was embedded in.
+	CrossDomain bool
+	// Redirect reports whether the URL is cross-domain and in a parameter commonly
+	// used for redirects, such as "next" or "return_to", making the outer URL an
+	// open redirect candidate.
+	Redirect bool
+}
+
+// maxPercentDecoding limits how many layers of percent-encoding are removed from
+// a parameter value.
+const maxPercentDecoding = 3
+
+// redirectParams are parameter names commonly holding a redirect destination.
+var redirectParams = map[string]bool{
+	"next": true, "url": true, "uri": true, "u": true, "r": true, "to": true,
+	"redirect": true, "redirect_uri": true, "redirect_url": true, "redirecturl": true, "redirecturi": true,
+	"redir": true, "return": true, "return_to": true, "returnto": true, "return_url": true, "returnurl": true,
+	"continue": true, "dest": true, "destination": true, "target": true, "goto": true, "go": true,
+	"out": true, "link": true, "forward": true, "callback": true, "rurl": true, "success_url": true,
+	"login_url": true, "logout_url": true, "checkout_url": true, "service": true,
+}
+
+// FindEmbedded finds URLs nested in the query parameters and fragment of raw, plain,
+// percent-encoded or base64-encoded, and validates them with ValidateDomain.
+func FindEmbedded(raw string) []EmbeddedURL {
+	return defaultValidator.FindEmbedded(raw)
+}
+
+// FindEmbedded works like the package-level FindEmbedded, validating with v.
+func (v *Validator) FindEmbedded(raw string) []EmbeddedURL {
+	outer := v.ValidateDomain(raw)
+	if !outer.Valid || outer.URL == nil {
+		return nil
+	}
+
+	var embedded []EmbeddedURL
+	for _, p := range splitParams(outer.URL.RawQuery) {
+		if e, ok := v.embeddedURL(p.name, p.value, outer); ok {
+			embedded = append(embedded, e)
+		}
+	}
+
+	// Fragments hold parameters too, "#!a=b" or "#/route?a=b" in single-page apps
+	fragment := outer.URL.EscapedFragment()
+	if strings.Contains(fragment, "=") {
+		if _, query, ok := strings.Cut(fragment, "?"); ok {
+			fragment = query
+		}
+		for _, p := range splitParams(strings.TrimPrefix(fragment, "!")) {
+			if e, ok := v.embeddedURL(p.name, p.value, outer); ok {
+				e.Fragment = true
+				embedded = append(embedded, e)
+			}
+		}
+	} else if fragment != "" {
+		if e, ok := v.embeddedURL("", fragment, outer); ok {
+			e.Fragment = true
+			embedded = append(embedded, e)
+		}
+	}
+
+	return embedded
+}
+
+type param struct {
+	name, value string // The name is decoded, the value is as written
+}
+
+// splitParams splits a query into its parameters in order. url.ParseQuery returns
+// a map and decodes the values, but the order and the raw value are needed here.
+func splitParams(query string) []param {
+	var params []param
+	for query != "" {
+		var pair string
+		pair, query, _ = strings.Cut(query, "&")
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		params = append(params, param{name: name, value: value})
+	}
+	return params
+}
+
+// embeddedURL decodes and validates the URL in a parameter value.
+func (v *Validator) embeddedURL(name, value string, outer ValidationResult) (EmbeddedURL, bool) {
+	redirect := redirectParams[strings.ToLower(name)]
+	decoded, encoding, ok := decodeEmbedded(value, redirect)
+	if !ok {
+		return EmbeddedURL{}, false
+	}
+
+	target := decoded
+	if strings.HasPrefix(target, "//") {
+		target = "https:" + target // Protocol-relative
+	}
+	result := v.ValidateDomain(target)
+	if !result.Valid {
+		return EmbeddedURL{}, false
+	}
+
+	e := EmbeddedURL{
+		Param:    name,
+		Encoding: encoding,
+		URL:      decoded,
+		Result:   result,
+	}
+	e.CrossDomain = site(hostOf(result)) != site(hostOf(outer))
+	e.Redirect = e.CrossDomain && redirect
+	return e, true
+}
+
+// hostOf returns the host a result was validated for, the address for IPs.
+func hostOf(result ValidationResult) string {
+	if result.ASCIIHost != "" {
+		return result.ASCIIHost
+	}
+	return result.TLD
+}
+
+// decodeEmbedded decodes a parameter value that looks like a URL. Bare domains without
+// a scheme are only taken from redirect parameters, elsewhere they are too often
+// something else, such as a search term.
+func decodeEmbedded(value string, redirect bool) (string, Encoding, bool) {
+	encoding := EncodingPlain
+	decoded := value
+	for i := 0; i < maxPercentDecoding && strings.Contains(decoded, "%"); i++ {
+		unescaped, err := url.QueryUnescape(decoded)
+		if err != nil {
+			break
+		}
+		decoded, encoding = unescaped, EncodingPercent
+	}
+	decoded = strings.TrimSpace(decoded)
+
+	if hasURLPrefix(decoded) || redirect && isBareDomain(decoded) {
+		return decoded, encoding, true
+	}
+
+	// '+' is part of the base64 alphabet, so start over from the raw value
+	raw, err := url.PathUnescape(value)
+	if err != nil {
+		return "", 0, false
+	}
+	if b64, ok := decodeBase64(raw); ok && hasURLPrefix(b64) {
+		return b64, EncodingBase64, true
+	}
+	return "", 0, false
+}
+
+// hasURLPrefix reports whether s starts like an absolute or protocol-relative URL.
+func hasURLPrefix(s string) bool {
+	lower := strings.ToLower(s)
+	for _, prefix := range []string{"http://", "https://", "ftp://", "//", "www."} {
+		if strings.HasPrefix(lower, prefix) && len(s) > len(prefix) {
+			return !strings.ContainsFunc(s, unicode.IsSpace)
+		}
+	}
+	return false
+}
+
+func isBareDomain(s string) bool {
+	return strings.Contains(s, ".") && !strings.HasPrefix(s, "/") && !strings.ContainsFunc(s, unicode.IsSpace)
+}
+
+// decodeBase64 decodes s in any of the base64 alphabets, with or without padding,
+// if the result is printable text.
+func decodeBase64(s string) (string, bool) {
+	if len(s) < 12 {
+		return "", false
+	}
+	s = strings.TrimRight(s, "=")
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	data, err := encoding.DecodeString(s)
+	if err != nil {
+		return "", false
+	}
+	text := string(data)
+	if strings.ContainsFunc(text, func(r rune) bool { return !unicode.IsPrint(r) }) {
+		return "", false
+	}
+	return text, true
+}
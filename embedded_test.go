@@ -0,0 +1,100 @@
+package urlverify
+
+import (
+	"testing"
+)
+
+func TestFindEmbedded(t *testing.T) {
+	type want struct {
+		param    string
+		fragment bool
+		encoding Encoding
+		url      string
+		cross    bool
+		redirect bool
+	}
+	tests := []struct {
+		input string
+		want  []want
+	}{
+		{"https://trusted.example.com/login?next=https://evil.example.net/", []want{
+			{"next", false, EncodingPlain, "https://evil.example.net/", true, true},
+		}},
+		{"https://trusted.example.com/login?lang=en&redirect_uri=https%3A%2F%2Fevil.example.net%2Fcb%3Fx%3D1", []want{
+			{"redirect_uri", false, EncodingPercent, "https://evil.example.net/cb?x=1", true, true},
+		}},
+		{"https://trusted.example.com/go?url=https%253A%252F%252Fevil.example.net%252F", []want{
+			{"url", false, EncodingPercent, "https://evil.example.net/", true, true},
+		}},
+		{"https://trusted.example.com/r?data=aHR0cHM6Ly9ldmlsLmV4YW1wbGUubmV0L2xvZ2lu", []want{
+			{"data", false, EncodingBase64, "https://evil.example.net/login", true, false},
+		}},
+		{"https://trusted.example.com/r?to=aHR0cHM6Ly9ldmlsLmV4YW1wbGUubmV0Lz9hPTE-", []want{
+			{"to", false, EncodingBase64, "https://evil.example.net/?a=1>", true, true},
+		}},
+		{"https://trusted.example.com/login?return_to=//evil.example.net/x", []want{
+			{"return_to", false, EncodingPlain, "//evil.example.net/x", true, true},
+		}},
+		{"https://trusted.example.com/login?continue=evil.example.net", []want{
+			{"continue", false, EncodingPlain, "evil.example.net", true, true},
+		}},
+		{"https://www.trusted.example.com/login?next=https://accounts.trusted.example.com/home", []want{
+			{"next", false, EncodingPlain, "https://accounts.trusted.example.com/home", false, false},
+		}},
+		{"https://app.example.com/#/login?redirect=https%3A%2F%2Fevil.example.net", []want{
+			{"redirect", true, EncodingPercent, "https://evil.example.net", true, true},
+		}},
+		{"https://app.example.com/page#https://evil.example.net/", []want{
+			{"", true, EncodingPlain, "https://evil.example.net/", true, false},
+		}},
+		{"https://search.example.com/?q=example.org&page=2", nil},
+		{"https://example.com/?next=/dashboard&id=aGVsbG8gd29ybGQgaGVsbG8=", nil},
+		{"https://example.com/?next=https://not valid", nil},
+		{"not a url", nil},
+	}
+
+	for _, tt := range tests {
+		got := FindEmbedded(tt.input)
+		if len(got) != len(tt.want) {
+			t.Errorf("FindEmbedded(%q) returned %d URLs, want %d: %+v", tt.input, len(got), len(tt.want), got)
+			continue
+		}
+		for i, w := range tt.want {
+			g := got[i]
+			if g.Param != w.param || g.Fragment != w.fragment || g.Encoding != w.encoding || g.URL != w.url ||
+				g.CrossDomain != w.cross || g.Redirect != w.redirect {
+				t.Errorf("FindEmbedded(%q)[%d] = {%q %v %v %q %v %v}, want %+v", tt.input, i,
+					g.Param, g.Fragment, g.Encoding, g.URL, g.CrossDomain, g.Redirect, w)
+			}
+			if !g.Result.Valid {
+				t.Errorf("FindEmbedded(%q)[%d] result invalid", tt.input, i)
+			}
+		}
+	}
+}
+
+func TestEncodingString(t *testing.T) {
+	if EncodingBase64.String() != "Base64" || Encoding(99).String() != "Unknown" {
+		t.Errorf("String() = %q, %q", EncodingBase64, Encoding(99))
+	}
+}
+
+func TestExtractorEmbeddedURLs(t *testing.T) {
+	text := "Reset your password at https://trusted.example.com/login?next=https%3A%2F%2Fevil.example.net%2F today, or see example.org."
+
+	matches := NewExtractor(WithEmbeddedURLs()).FindAll(text)
+	if len(matches) != 2 {
+		t.Fatalf("FindAll() returned %d matches, want 2", len(matches))
+	}
+	embedded := matches[0].Embedded
+	if len(embedded) != 1 || !embedded[0].Redirect || embedded[0].Result.ASCIIHost != "evil.example.net" {
+		t.Errorf("Embedded = %+v", embedded)
+	}
+	if matches[1].Embedded != nil {
+		t.Errorf("Embedded of a bare domain = %+v", matches[1].Embedded)
+	}
+
+	if NewExtractor().FindAll(text)[0].Embedded != nil {
+		t.Error("Embedded set without WithEmbeddedURLs")
+	}
+}
@@ -0,0 +1,199 @@
+package urlverify
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// escapedSchemeRegex finds "http://" and "https://", plain or with the colon and slashes
+// escaped for JSON, JavaScript, URL encoding or HTML.
+var escapedSchemeRegex = regexp.MustCompile(`(?i)https?(?::|%3A|\\u003A|\\x3A|&#0*58;|&#x0*3A;|&colon;)(?:\\?/|%2F|\\u002F|\\x2F|&#0*47;|&#x0*2F;|&sol;){2}`)
+
+// findEscaped finds URLs written in escaped form and returns them decoded,
+// with Start and End covering the escaped text.
+func findEscaped(text string, v *Validator) []Match {
+	var matches []Match
+
+	for pos := 0; pos < len(text); {
+		loc := escapedSchemeRegex.FindStringIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		prefix := text[start : pos+loc[1]]
+		decoded, offsets := decodeEscapedURL(text[start:], strings.Contains(prefix, "%"))
+		pos = start + offsets[len(decoded)]
+
+		raw := strings.TrimRight(decoded, ".,)") // Strip trailing punctuation
+		end := start + offsets[len(raw)]
+		if text[start:end] == raw {
+			// Nothing was escaped, the plain scan takes care of it
+			continue
+		}
+		result := v.ValidateDomain(raw)
+		if !result.Valid {
+			continue
+		}
+
+		m := Match{Text: raw, Start: start, End: end, Encoded: text[start:end], Result: result}
+		scoreMatch(text, &m)
+		matches = append(matches, m)
+	}
+
+	return matches
+}
+
+// decodeEscapedURL decodes the escaped URL at the start of s, which starts with a scheme
+// matched by escapedSchemeRegex, up to the first character that can't be part of it or
+// where urlBoundary ends it. offsets maps every byte of the decoded string, and the end
+// of it, to the corresponding position in s. Percent-encoding is only decoded if
+// percent is set, in which case a literal '&' ends the URL as it separates the
+// parameters of the enclosing query.
+func decodeEscapedURL(s string, percent bool) (string, []int) {
+	var (
+		b       strings.Builder
+		offsets []int
+		i       int
+	)
+
+	hostStart := len("http://")
+	if s[4] == 's' || s[4] == 'S' {
+		hostStart++
+	}
+	bounds := newURLBoundary(hostStart)
+
+	for i < len(s) {
+		r, n := decodeEscape(s[i:], percent)
+		if n == 0 {
+			r, n = utf8.DecodeRuneInString(s[i:])
+			if percent && r == '&' {
+				break
+			}
+		}
+		if r == utf8.RuneError && n <= 1 || isURLTerminator(r) {
+			break
+		}
+		rest := s[i+n:]
+		if isIDNADot(r) {
+			// The label after the dot may be escaped as well
+			if next, k := decodeEscape(rest, percent); k > 0 {
+				rest = string(next)
+			}
+		}
+		if bounds.ends(b.Len(), r, rest) {
+			break
+		}
+
+		before := b.Len()
+		b.WriteRune(r)
+		for k := before; k < b.Len(); k++ {
+			offsets = append(offsets, i)
+		}
+		i += n
+	}
+
+	return b.String(), append(offsets, i)
+}
+
+// decodeEscape decodes a single escape sequence at the start of s and returns the decoded
+// rune and the length of the sequence, or n == 0 if s doesn't start with an escape.
+// Escapes that stand for a string terminator, such as \" or \n, decode to a space.
+func decodeEscape(s string, percent bool) (r rune, n int) {
+	switch {
+	case strings.HasPrefix(s, `\`) && len(s) > 1:
+		switch c := s[1]; c {
+		case '/':
+			return '/', 2
+		case 'u', 'U':
+			if strings.HasPrefix(s[2:], "{") {
+				if end := strings.IndexByte(s, '}'); end > 3 && end <= 9 {
+					if v, err := strconv.ParseUint(s[3:end], 16, 32); err == nil {
+						return rune(v), end + 1
+					}
+				}
+				return ' ', 1
+			}
+			if len(s) < 6 {
+				return ' ', 1
+			}
+			v, err := strconv.ParseUint(s[2:6], 16, 16)
+			if err != nil {
+				return ' ', 1
+			}
+			r = rune(v)
+			if utf16.IsSurrogate(r) && len(s) >= 12 && s[6] == '\\' && s[7] == 'u' {
+				if lo, err := strconv.ParseUint(s[8:12], 16, 16); err == nil {
+					return utf16.DecodeRune(r, rune(lo)), 12
+				}
+			}
+			return r, 6
+		case 'x', 'X':
+			if len(s) >= 4 {
+				if v, err := strconv.ParseUint(s[2:4], 16, 8); err == nil && v < utf8.RuneSelf {
+					return rune(v), 4
+				}
+			}
+			return ' ', 1
+		default:
+			// \", \n and friends end the enclosing string literal
+			return ' ', 1
+		}
+
+	case percent && s[0] == '%' && len(s) >= 3:
+		v, err := strconv.ParseUint(s[1:3], 16, 8)
+		if err != nil {
+			return 0, 0
+		}
+		if v < utf8.RuneSelf {
+			return rune(v), 3
+		}
+		// Percent-encoded UTF-8 sequence
+		var buf []byte
+		k := 0
+		for k+3 <= len(s) && s[k] == '%' && len(buf) < utf8.UTFMax {
+			b, err := strconv.ParseUint(s[k+1:k+3], 16, 8)
+			if err != nil {
+				break
+			}
+			buf = append(buf, byte(b))
+			k += 3
+			if utf8.FullRune(buf) {
+				break
+			}
+		}
+		if r, size := utf8.DecodeRune(buf); r != utf8.RuneError && size == len(buf) {
+			return r, k
+		}
+		return 0, 0
+
+	case s[0] == '&':
+		end := strings.IndexByte(s, ';')
+		if end < 2 || end > 10 {
+			return 0, 0
+		}
+		entity := s[:end+1]
+		decoded := html.UnescapeString(entity)
+		if decoded == entity {
+			return 0, 0
+		}
+		r, size := utf8.DecodeRuneInString(decoded)
+		if size != len(decoded) {
+			return 0, 0
+		}
+		return r, end + 1
+	}
+
+	return 0, 0
+}
+
+func isURLTerminator(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\f', '\v', '"', '\'', '<', '>', '`':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package urlverify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeDecoding(t *testing.T) {
+	text := `{"url":"https:\/\/example.com\/path?a=1&b=2","next":"x"} ` +
+		`var u = "https://café.example.org\x2Findex";` + "\n" +
+		`GET /login?next=https%3A%2F%2Fexample.net%2Faccount%3Fid%3D7&lang=en HTTP/1.1` + "\n" +
+		`<a href="https&#x3A;&#x2F;&#x2F;entity.example.com&sol;x">link</a> and plain https://github.com`
+
+	expected := []struct {
+		text    string
+		encoded string
+	}{
+		{"https://example.com/path?a=1&b=2", `https:\/\/example.com\/path?a=1&b=2`},
+		{"https://café.example.org/index", `https://café.example.org\x2Findex`},
+		{"https://example.net/account?id=7", `https%3A%2F%2Fexample.net%2Faccount%3Fid%3D7`},
+		{"https://entity.example.com/x", `https&#x3A;&#x2F;&#x2F;entity.example.com&sol;x`},
+		{"https://github.com", ""},
+	}
+
+	result := NewExtractor(WithEscapeDecoding()).FindAll(text)
+
+	if len(result) != len(expected) {
+		t.Errorf("FindAll() returned %d matches, want %d", len(result), len(expected))
+		for _, m := range result {
+			t.Logf("Got: %q from %q", m.Text, m.Encoded)
+		}
+		return
+	}
+
+	for i, want := range expected {
+		got := result[i]
+		if got.Text != want.text || got.Encoded != want.encoded {
+			t.Errorf("FindAll() result[%d] = {%q %q}, want {%q %q}", i, got.Text, got.Encoded, want.text, want.encoded)
+		}
+		original := want.encoded
+		if original == "" {
+			original = want.text
+		}
+		if text[got.Start:got.End] != original {
+			t.Errorf("FindAll() result[%d] span = %q, want %q", i, text[got.Start:got.End], original)
+		}
+	}
+}
+
+func TestEscapeDecodingDisabled(t *testing.T) {
+	text := `"https:\/\/example.com\/path"`
+
+	for _, m := range NewExtractor().FindAll(text) {
+		if m.Encoded != "" {
+			t.Errorf("FindAll() decoded %q without WithEscapeDecoding", m.Encoded)
+		}
+	}
+}
+
+func TestEscapeDecodingSpans(t *testing.T) {
+	e := NewExtractor(WithEscapeDecoding())
+
+	text := `詳細は"https:\/\/example.com\/pageをご覧ください","https:\/\/example.org\/docs"`
+	var got []string
+	for _, m := range e.FindAll(text) {
+		got = append(got, m.Text)
+	}
+	if want := "https://example.com/page https://example.org/docs"; strings.Join(got, " ") != want {
+		t.Errorf("FindAll() = %q, want %s", got, want)
+	}
+
+	// Every scheme is decoded only as far as its URL goes
+	text = strings.Repeat(`https:\/\/`, 10000)
+	if result := e.FindAll(text); len(result) != 0 {
+		t.Errorf("FindAll() of repeated schemes = %d matches", len(result))
+	}
+}
+
+func BenchmarkEscapeDecoding_SpacelessText(b *testing.B) {
+	e := NewExtractor(WithEscapeDecoding())
+	text := strings.Repeat(`詳しくは"https:\/\/example.com\/page"をご覧ください。またhttps://example.org/docsも参照、`, 500)
+	for i := 0; i < b.N; i++ {
+		e.FindAll(text)
+	}
+}
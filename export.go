@@ -0,0 +1,330 @@
+package urlverify
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportOption configures ExportSTIX and ExportMISP.
+type ExportOption func(*exportConfig)
+
+type exportConfig struct {
+	now  time.Time
+	text string
+	info string
+}
+
+// WithExportTime sets the creation time of the exported objects, the current time by
+// default. With a fixed time the output is identical for the same matches.
+func WithExportTime(t time.Time) ExportOption {
+	return func(c *exportConfig) {
+		c.now = t
+	}
+}
+
+// WithExportText passes the text the matches were found in, so that matches of the
+// domain of an email address are exported as the address. Without it they are
+// exported as domains.
+func WithExportText(text string) ExportOption {
+	return func(c *exportConfig) {
+		c.text = text
+	}
+}
+
+// WithEventInfo sets the info line of the exported MISP event.
+func WithEventInfo(info string) ExportOption {
+	return func(c *exportConfig) {
+		c.info = info
+	}
+}
+
+// stixNamespace is the namespace STIX 2.1 defines for the UUIDv5 identifiers of
+// cyber observable objects. The IDs of all other exported objects derive from it too.
+var stixNamespace = [16]byte{0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c, 0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7}
+
+// observable is a match reduced to a STIX cyber observable.
+type observable struct {
+	Type  string // STIX object type: "url", "domain-name", "ipv4-addr", "ipv6-addr" or "email-addr"
+	Value string
+}
+
+// observables maps matches to observables, skipping duplicates. URLType decides the
+// object type: IP addresses standing alone become ipv4-addr or ipv6-addr, domains
+// standing alone domain-name, or email-addr after an '@' in text, and matches with a
+// scheme, port, path or query url.
+func observables(matches []Match, text string) []observable {
+	var result []observable
+	seen := make(map[observable]bool)
+	for i := range matches {
+		m := &matches[i]
+		o, ok := observableOf(m, text)
+		if ok && !seen[o] {
+			seen[o] = true
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+func observableOf(m *Match, text string) (observable, bool) {
+	r := m.Result
+	if !r.Valid || r.URL == nil {
+		return observable{}, false
+	}
+
+	u := r.URL
+	bare := !strings.Contains(m.Text, "://") && r.Port == 0 && (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == ""
+	switch {
+	case !bare:
+		return observable{"url", u.String()}, true
+	case r.Type == URLTypeIP:
+		addr, err := netip.ParseAddr(r.TLD)
+		if err != nil {
+			return observable{}, false
+		}
+		if addr.Is4() {
+			return observable{"ipv4-addr", addr.String()}, true
+		}
+		return observable{"ipv6-addr", addr.String()}, true
+	case r.Type == URLTypeICANN || r.Type == URLTypeNonICANN:
+		if local := emailLocalPart(text, m.Start); local != "" {
+			return observable{"email-addr", local + "@" + r.ASCIIHost}, true
+		}
+		return observable{"domain-name", r.ASCIIHost}, true
+	default:
+		return observable{}, false
+	}
+}
+
+// emailLocalPart returns the local part of the email address whose domain starts at
+// offset start of text, empty if the domain doesn't follow an '@'.
+func emailLocalPart(text string, start int) string {
+	if start <= 0 || start > len(text) || text[start-1] != '@' {
+		return ""
+	}
+	i := start - 1
+	for i > 0 && isAtext(text[i-1]) {
+		i--
+	}
+	return strings.Trim(text[i:start-1], ".")
+}
+
+// isAtext reports whether c may appear in the dot-atom local part of an address (RFC 5322 3.2.3).
+func isAtext(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("!#$%&'*+/=?^_`{|}~-.", c) >= 0
+}
+
+// canonicalValue returns the JSON object of the value property of an observable in
+// the canonical form (RFC 8785) its ID is derived from.
+func canonicalValue(value string) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return `{"value":` + strings.TrimSuffix(b.String(), "\n") + "}"
+}
+
+// uuid5 returns the name-based UUID (RFC 9562 5.5) of name in namespace.
+func uuid5(namespace [16]byte, name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(u[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+type stixBundle struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Objects []any  `json:"objects"`
+}
+
+type stixObservable struct {
+	Type        string `json:"type"`
+	SpecVersion string `json:"spec_version"`
+	ID          string `json:"id"`
+	Value       string `json:"value"`
+}
+
+type stixIndicator struct {
+	Type           string   `json:"type"`
+	SpecVersion    string   `json:"spec_version"`
+	ID             string   `json:"id"`
+	Created        string   `json:"created"`
+	Modified       string   `json:"modified"`
+	Name           string   `json:"name"`
+	IndicatorTypes []string `json:"indicator_types"`
+	Pattern        string   `json:"pattern"`
+	PatternType    string   `json:"pattern_type"`
+	ValidFrom      string   `json:"valid_from"`
+}
+
+type stixRelationship struct {
+	Type             string `json:"type"`
+	SpecVersion      string `json:"spec_version"`
+	ID               string `json:"id"`
+	Created          string `json:"created"`
+	Modified         string `json:"modified"`
+	RelationshipType string `json:"relationship_type"`
+	SourceRef        string `json:"source_ref"`
+	TargetRef        string `json:"target_ref"`
+}
+
+// stixTime is the timestamp format of STIX 2.1, in UTC with milliseconds.
+const stixTime = "2006-01-02T15:04:05.000Z"
+
+// ExportSTIX writes the matches to w as a STIX 2.1 bundle. Every distinct URL, domain,
+// IP address and email address becomes a cyber observable (url, domain-name,
+// ipv4-addr, ipv6-addr or email-addr), an indicator with a pattern matching it and a
+// related-to relationship between them. All IDs are UUIDv5 derived from the values,
+// observables as STIX defines, so the same value always gets the same IDs.
+func ExportSTIX(w io.Writer, matches []Match, opts ...ExportOption) error {
+	c := newExportConfig(opts)
+	now := c.now.UTC().Format(stixTime)
+
+	var objects []any
+	var ids []string
+	for _, o := range observables(matches, c.text) {
+		sco := stixObservable{
+			Type:        o.Type,
+			SpecVersion: "2.1",
+			ID:          o.Type + "--" + uuid5(stixNamespace, canonicalValue(o.Value)),
+			Value:       o.Value,
+		}
+		pattern := "[" + o.Type + ":value = '" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(o.Value) + "']"
+		indicator := stixIndicator{
+			Type:           "indicator",
+			SpecVersion:    "2.1",
+			ID:             "indicator--" + uuid5(stixNamespace, pattern),
+			Created:        now,
+			Modified:       now,
+			Name:           o.Value,
+			IndicatorTypes: []string{"unknown"},
+			Pattern:        pattern,
+			PatternType:    "stix",
+			ValidFrom:      now,
+		}
+		relationship := stixRelationship{
+			Type:             "relationship",
+			SpecVersion:      "2.1",
+			ID:               "relationship--" + uuid5(stixNamespace, indicator.ID+" related-to "+sco.ID),
+			Created:          now,
+			Modified:         now,
+			RelationshipType: "related-to",
+			SourceRef:        indicator.ID,
+			TargetRef:        sco.ID,
+		}
+		objects = append(objects, sco, indicator, relationship)
+		ids = append(ids, sco.ID)
+	}
+	if objects == nil {
+		objects = []any{}
+	}
+
+	bundle := stixBundle{
+		Type:    "bundle",
+		ID:      "bundle--" + uuid5(stixNamespace, strings.Join(ids, ",")),
+		Objects: objects,
+	}
+	return encodeExport(w, bundle)
+}
+
+// mispTypes maps the STIX object types to MISP attribute types.
+var mispTypes = map[string]string{
+	"url":         "url",
+	"domain-name": "domain",
+	"ipv4-addr":   "ip-dst",
+	"ipv6-addr":   "ip-dst",
+	"email-addr":  "email",
+}
+
+type mispEvent struct {
+	Event mispEventBody `json:"Event"`
+}
+
+type mispEventBody struct {
+	UUID          string          `json:"uuid"`
+	Info          string          `json:"info"`
+	Date          string          `json:"date"`
+	Timestamp     string          `json:"timestamp"`
+	ThreatLevelID string          `json:"threat_level_id"`
+	Analysis      string          `json:"analysis"`
+	Distribution  string          `json:"distribution"`
+	Published     bool            `json:"published"`
+	Attribute     []mispAttribute `json:"Attribute"`
+}
+
+type mispAttribute struct {
+	UUID      string `json:"uuid"`
+	Type      string `json:"type"`
+	Category  string `json:"category"`
+	Value     string `json:"value"`
+	ToIDS     bool   `json:"to_ids"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ExportMISP writes the matches to w as a MISP event in the JSON format of the MISP
+// API and feeds. Every distinct URL, domain, IP address and email address becomes an
+// attribute of type url, domain, ip-dst or email in the Network activity category,
+// flagged for IDS. The event and attribute UUIDs are UUIDv5 derived from the values,
+// so the same matches always get the same UUIDs.
+func ExportMISP(w io.Writer, matches []Match, opts ...ExportOption) error {
+	c := newExportConfig(opts)
+	timestamp := strconv.FormatInt(c.now.Unix(), 10)
+
+	attributes := []mispAttribute{}
+	var values []string
+	for _, o := range observables(matches, c.text) {
+		kind := mispTypes[o.Type]
+		attributes = append(attributes, mispAttribute{
+			UUID:      uuid5(stixNamespace, "misp-attribute:"+kind+":"+o.Value),
+			Type:      kind,
+			Category:  "Network activity",
+			Value:     o.Value,
+			ToIDS:     true,
+			Timestamp: timestamp,
+		})
+		values = append(values, kind+":"+o.Value)
+	}
+
+	event := mispEvent{Event: mispEventBody{
+		UUID:          uuid5(stixNamespace, "misp-event:"+c.info+"\n"+strings.Join(values, "\n")),
+		Info:          c.info,
+		Date:          c.now.UTC().Format(time.DateOnly),
+		Timestamp:     timestamp,
+		ThreatLevelID: "4", // Undefined
+		Analysis:      "0", // Initial
+		Distribution:  "0", // Your organisation only
+		Attribute:     attributes,
+	}}
+	return encodeExport(w, event)
+}
+
+func newExportConfig(opts []ExportOption) *exportConfig {
+	c := &exportConfig{info: "Extracted indicators"}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.now.IsZero() {
+		c.now = time.Now()
+	}
+	return c
+}
+
+func encodeExport(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,154 @@
+package urlverify
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const exportText = "Payload http://evil.example.com/a?b=1&c=2 from 198.51.100.7 and 198.51.100.7, " +
+	"report to abuse.desk@example.com or www.example.org [2001:db8::1]"
+
+var exportTime = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func TestExportSTIX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportSTIX(&buf, FindAll(exportText), WithExportText(exportText), WithExportTime(exportTime)); err != nil {
+		t.Fatal(err)
+	}
+	var bundle struct {
+		Type    string
+		ID      string
+		Objects []map[string]any
+	}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Type != "bundle" || !strings.HasPrefix(bundle.ID, "bundle--") || len(bundle.Objects) != 15 {
+		t.Fatalf("bundle %s %s with %d objects", bundle.Type, bundle.ID, len(bundle.Objects))
+	}
+
+	// Observable IDs follow the STIX UUIDv5 scheme, as computed by other implementations
+	want := []string{
+		"url--2c7074e2-819b-5c8e-aaea-7f7833ae75bd",
+		"ipv4-addr--12592121-be4c-5a93-a813-5a5af16e50db",
+		"email-addr--2645fa6e-4ad6-5ca4-9172-dade00a6d208",
+		"domain-name--6d0535cc-be4a-5b19-8eb8-af15195817e6",
+		"ipv6-addr--6469e3a9-b053-5e34-a025-9396ae051d26",
+	}
+	for i, id := range want {
+		sco, indicator, relationship := bundle.Objects[3*i], bundle.Objects[3*i+1], bundle.Objects[3*i+2]
+		if sco["id"] != id || sco["spec_version"] != "2.1" {
+			t.Errorf("observable %d = %v, want id %s", i, sco, id)
+		}
+		pattern := "[" + sco["type"].(string) + ":value = '" + sco["value"].(string) + "']"
+		if indicator["type"] != "indicator" || indicator["pattern"] != pattern || indicator["valid_from"] != "2024-07-01T12:00:00.000Z" {
+			t.Errorf("indicator %d = %v", i, indicator)
+		}
+		if relationship["source_ref"] != indicator["id"] || relationship["target_ref"] != id {
+			t.Errorf("relationship %d = %v", i, relationship)
+		}
+	}
+
+	// IDs don't depend on the time, output does
+	var later bytes.Buffer
+	ExportSTIX(&later, FindAll(exportText), WithExportText(exportText), WithExportTime(exportTime.Add(time.Hour)))
+	if !strings.Contains(later.String(), bundle.ID) || !strings.Contains(later.String(), want[0]) || later.String() == buf.String() {
+		t.Error("IDs changed with the export time")
+	}
+
+	var again bytes.Buffer
+	ExportSTIX(&again, FindAll(exportText), WithExportText(exportText), WithExportTime(exportTime))
+	if again.String() != buf.String() {
+		t.Error("export isn't deterministic")
+	}
+}
+
+func TestExportSTIXTypes(t *testing.T) {
+	tests := []struct {
+		text, typ, value string
+	}{
+		{"example.com", "domain-name", "example.com"},
+		{"https://example.com", "url", "https://example.com"},
+		{"example.com/path", "url", "http://example.com/path"},
+		{"example.com:8080", "url", "http://example.com:8080"},
+		{"http://198.51.100.7/", "url", "http://198.51.100.7/"},
+		{"myhome.duckdns.org", "domain-name", "myhome.duckdns.org"},
+		{"mail user@example.com", "domain-name", "example.com"}, // Without the text
+		{"bücher.de", "domain-name", "xn--bcher-kva.de"},
+		{"evil.example.com/it's", "url", "http://evil.example.com/it's"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := ExportSTIX(&buf, FindAll(tt.text)); err != nil {
+			t.Fatal(err)
+		}
+		var bundle struct{ Objects []map[string]any }
+		json.Unmarshal(buf.Bytes(), &bundle)
+		if len(bundle.Objects) != 3 || bundle.Objects[0]["type"] != tt.typ || bundle.Objects[0]["value"] != tt.value {
+			t.Errorf("ExportSTIX(%q) objects = %v, want %s %s", tt.text, bundle.Objects, tt.typ, tt.value)
+		}
+	}
+	var quoted bytes.Buffer
+	ExportSTIX(&quoted, FindAll("evil.example.com/it's"))
+	if !strings.Contains(quoted.String(), `"pattern": "[url:value = 'http://evil.example.com/it\\'s']"`) {
+		t.Errorf("pattern quote isn't escaped: %s", quoted.String())
+	}
+
+	var buf bytes.Buffer
+	ExportSTIX(&buf, nil)
+	if !strings.Contains(buf.String(), `"objects": []`) {
+		t.Errorf("empty bundle = %s", buf.String())
+	}
+}
+
+func TestExportMISP(t *testing.T) {
+	var buf bytes.Buffer
+	err := ExportMISP(&buf, FindAll(exportText), WithExportText(exportText), WithExportTime(exportTime), WithEventInfo("Phishing wave"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event struct {
+		Event struct {
+			UUID      string `json:"uuid"`
+			Info      string `json:"info"`
+			Date      string `json:"date"`
+			Timestamp string `json:"timestamp"`
+			Attribute []struct {
+				UUID  string `json:"uuid"`
+				Type  string `json:"type"`
+				Value string `json:"value"`
+				ToIDS bool   `json:"to_ids"`
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatal(err)
+	}
+	e := event.Event
+	if e.Info != "Phishing wave" || e.Date != "2024-07-01" || e.Timestamp != "1719835200" || len(e.UUID) != 36 {
+		t.Errorf("event = %+v", e)
+	}
+	want := []string{"url http://evil.example.com/a?b=1&c=2", "ip-dst 198.51.100.7", "email abuse.desk@example.com", "domain www.example.org", "ip-dst 2001:db8::1"}
+	if len(e.Attribute) != len(want) {
+		t.Fatalf("%d attributes, want %d", len(e.Attribute), len(want))
+	}
+	uuids := make(map[string]bool)
+	for i, a := range e.Attribute {
+		if a.Type+" "+a.Value != want[i] || !a.ToIDS {
+			t.Errorf("attribute %d = %+v, want %s", i, a, want[i])
+		}
+		uuids[a.UUID] = true
+	}
+	if len(uuids) != len(want) {
+		t.Errorf("attribute UUIDs aren't distinct: %v", uuids)
+	}
+
+	var again bytes.Buffer
+	ExportMISP(&again, FindAll(exportText), WithExportText(exportText), WithExportTime(exportTime), WithEventInfo("Phishing wave"))
+	if again.String() != buf.String() {
+		t.Error("export isn't deterministic")
+	}
+}
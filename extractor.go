@@ -0,0 +1,267 @@
+package urlverify
+
+import (
+	"context"
+	"sort"
+)
+
+// Extractor extracts URLs and domains from text with optional processing stages.
+// The zero value behaves like FindAll; use NewExtractor to enable stages.
+// An Extractor is safe for concurrent use once configured.
+type Extractor struct {
+	validator     *Validator
+	decodeEscapes bool
+	minConfidence float64
+	credentials   CredentialPolicy
+	allow, deny   *DomainSet
+	allowIP       *IPSet
+	denyIP        *IPSet
+	unwrap        *UnwrapRegistry
+	embedded      bool
+	resolve       *ResolveCheck
+	categorizer   *Categorizer
+	hashPrefixes  *HashPrefixDB
+	feeds         *FeedIndex
+}
+
+// ExtractorOption configures an Extractor.
+type ExtractorOption func(*Extractor)
+
+// NewExtractor creates an Extractor with the given options.
+func NewExtractor(opts ...ExtractorOption) *Extractor {
+	e := &Extractor{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// WithValidator makes the Extractor validate matches with v instead of ValidateDomain.
+func WithValidator(v *Validator) ExtractorOption {
+	return func(e *Extractor) {
+		e.validator = v
+	}
+}
+
+// WithEscapeDecoding makes the Extractor recognise URLs written in escaped form,
+// such as "https:\/\/example.com" in JSON, "https%3A%2F%2Fexample.com" in query strings
+// or "https:&#x2F;&#x2F;example.com" in HTML. Decoded matches hold the decoded URL in Text
+// and the original escaped text in Encoded; Start and End cover the escaped text.
+func WithEscapeDecoding() ExtractorOption {
+	return func(e *Extractor) {
+		e.decodeEscapes = true
+	}
+}
+
+// WithMinConfidence makes the Extractor drop matches with a Confidence below threshold.
+// The package-level ExtractAll and FindAll never filter by confidence.
+func WithMinConfidence(threshold float64) ExtractorOption {
+	return func(e *Extractor) {
+		e.minConfidence = threshold
+	}
+}
+
+// WithAllowList makes the Extractor keep only matches whose host is in set.
+// IP addresses never match a DomainSet and are dropped.
+func WithAllowList(set *DomainSet) ExtractorOption {
+	return func(e *Extractor) {
+		e.allow = set
+	}
+}
+
+// WithDenyList makes the Extractor drop matches whose host is in set.
+func WithDenyList(set *DomainSet) ExtractorOption {
+	return func(e *Extractor) {
+		e.deny = set
+	}
+}
+
+// WithIPAllowList makes the Extractor keep only IP address matches that fall into
+// a range of set, reporting the range in Result.IPPrefix. Domain matches are kept.
+func WithIPAllowList(set *IPSet) ExtractorOption {
+	return func(e *Extractor) {
+		e.allowIP = set
+	}
+}
+
+// WithIPDenyList makes the Extractor drop IP address matches that fall into a range of set.
+func WithIPDenyList(set *IPSet) ExtractorOption {
+	return func(e *Extractor) {
+		e.denyIP = set
+	}
+}
+
+// WithUnwrapping makes the Extractor replace link-protection and redirect wrapper URLs,
+// such as Safe Links or Proofpoint URL Defense links, with their destination using the
+// Unwrappers of r, or the built-in ones if r is nil. Unwrapped matches hold the
+// destination in Text and Result and the wrappers in Wrappers; Start and End cover
+// the wrapper text. Allow and deny lists apply to the destination.
+func WithUnwrapping(r *UnwrapRegistry) ExtractorOption {
+	return func(e *Extractor) {
+		if r == nil {
+			r = defaultUnwrapRegistry
+		}
+		e.unwrap = r
+	}
+}
+
+// WithEmbeddedURLs makes the Extractor look for URLs nested in the query parameters and
+// fragment of every match, reporting them in Match.Embedded. See FindEmbedded.
+func WithEmbeddedURLs() ExtractorOption {
+	return func(e *Extractor) {
+		e.embedded = true
+	}
+}
+
+// WithResolveCheck makes the Extractor resolve the host of every match it returns
+// through c, reporting the outcome in Match.Resolution. Hosts are resolved after all
+// other stages, so filtered matches aren't looked up. Use ResolveCheck.Check directly
+// to pass a context.
+func WithResolveCheck(c *ResolveCheck) ExtractorOption {
+	return func(e *Extractor) {
+		e.resolve = c
+	}
+}
+
+// WithCategorizer makes the Extractor report the categories of every match in
+// Match.Categories using c, or the built-in data if c is nil.
+func WithCategorizer(c *Categorizer) ExtractorOption {
+	return func(e *Extractor) {
+		if c == nil {
+			c = defaultCategorizer()
+		}
+		e.categorizer = c
+	}
+}
+
+// ExtractAll extracts all valid URLs and domains from text, see FindAll.
+func (e *Extractor) ExtractAll(text string) []string {
+	var validURLs []string
+	for _, m := range e.FindAll(text) {
+		validURLs = append(validURLs, m.Text)
+	}
+	return validURLs
+}
+
+// FindAll extracts all valid URLs and domains from text, applying the configured stages.
+func (e *Extractor) FindAll(text string) []Match {
+	v := e.validator
+	if v == nil {
+		v = defaultValidator
+	}
+
+	matches := findAll(text, v)
+	scoreMatches(text, matches)
+
+	if e.decodeEscapes {
+		matches = mergeMatches(matches, findEscaped(text, v))
+	}
+
+	if e.unwrap != nil {
+		unwrapMatches(matches, e.unwrap, v)
+	}
+
+	if e.allow != nil {
+		matches = filterMatches(matches, func(m *Match) bool {
+			return m.Result.ASCIIHost != "" && e.allow.Contains(m.Result.ASCIIHost)
+		})
+	}
+	if e.deny != nil {
+		matches = filterMatches(matches, func(m *Match) bool {
+			return m.Result.ASCIIHost == "" || !e.deny.Contains(m.Result.ASCIIHost)
+		})
+	}
+
+	if e.allowIP != nil {
+		matches = filterMatches(matches, func(m *Match) bool {
+			found, ok := lookupIP(e.allowIP, &m.Result)
+			return found || !ok
+		})
+	}
+	if e.denyIP != nil {
+		matches = filterMatches(matches, func(m *Match) bool {
+			found, _ := lookupIP(e.denyIP, &m.Result)
+			return !found
+		})
+	}
+
+	if e.embedded {
+		for i := range matches {
+			matches[i].Embedded = v.FindEmbedded(matches[i].Text)
+		}
+	}
+
+	matches = applyCredentialPolicy(matches, e.credentials)
+
+	if e.minConfidence > 0 {
+		matches = filterMatches(matches, func(m *Match) bool {
+			return m.Confidence >= e.minConfidence
+		})
+	}
+
+	if e.categorizer != nil {
+		for i := range matches {
+			if host := matches[i].Result.ASCIIHost; host != "" {
+				matches[i].Categories = e.categorizer.categorizeHost(host)
+			}
+		}
+	}
+
+	if e.hashPrefixes != nil {
+		for i := range matches {
+			matches[i].HashMatches = e.hashPrefixes.Lookup(matches[i].Text)
+		}
+	}
+
+	if e.feeds != nil {
+		for i := range matches {
+			matches[i].FeedMatches = e.feeds.Lookup(matches[i].Text)
+		}
+	}
+
+	if e.resolve != nil {
+		e.resolve.Check(context.Background(), matches)
+	}
+
+	return matches
+}
+
+// mergeMatches replaces the matches overlapping any of the preferred matches
+// and returns the result ordered by position.
+func mergeMatches(matches, preferred []Match) []Match {
+	if len(preferred) == 0 {
+		return matches
+	}
+
+	merged := make([]Match, 0, len(matches)+len(preferred))
+	for _, m := range matches {
+		overlaps := false
+		for _, p := range preferred {
+			if m.Start < p.End && p.Start < m.End {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			merged = append(merged, m)
+		}
+	}
+	merged = append(merged, preferred...)
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Start < merged[j].Start
+	})
+
+	return merged
+}
+
+// filterMatches keeps the matches for which keep returns true, reusing the slice.
+func filterMatches(matches []Match, keep func(*Match) bool) []Match {
+	kept := matches[:0]
+	for i := range matches {
+		if keep(&matches[i]) {
+			kept = append(kept, matches[i])
+		}
+	}
+	return kept
+}
@@ -0,0 +1,510 @@
+package urlverify
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FeedFormat is the format of a threat feed dump.
+type FeedFormat int
+
+const (
+	FeedURLhausCSV    FeedFormat = iota // URLhaus csv.txt dumps
+	FeedURLhausJSON                     // URLhaus json.txt dumps, entries keyed by id
+	FeedPhishTankCSV                    // PhishTank online-valid.csv dumps
+	FeedPhishTankJSON                   // PhishTank online-valid.json dumps
+	FeedOpenPhish                       // OpenPhish feed.txt, one URL per line
+	FeedOpenPhishJSON                   // OpenPhish JSON feeds, an array or one object per line
+)
+
+func (f FeedFormat) String() string {
+	switch f {
+	case FeedURLhausCSV:
+		return "URLhaus CSV"
+	case FeedURLhausJSON:
+		return "URLhaus JSON"
+	case FeedPhishTankCSV:
+		return "PhishTank CSV"
+	case FeedPhishTankJSON:
+		return "PhishTank JSON"
+	case FeedOpenPhish:
+		return "OpenPhish"
+	case FeedOpenPhishJSON:
+		return "OpenPhish JSON"
+	default:
+		return "Unknown"
+	}
+}
+
+// feed returns the name of the feed reported in FeedEntry.Feed.
+func (f FeedFormat) feed() string {
+	switch f {
+	case FeedURLhausCSV, FeedURLhausJSON:
+		return "URLhaus"
+	case FeedPhishTankCSV, FeedPhishTankJSON:
+		return "PhishTank"
+	default:
+		return "OpenPhish"
+	}
+}
+
+// FeedEntry is a URL listed by a threat feed.
+type FeedEntry struct {
+	URL    string // The URL as listed
+	Key    string // The URL normalized for exact matching, see FeedIndex
+	Host   string // ASCII host of the URL, or its IP address
+	Domain string // Registrable domain of the host, empty for IP addresses
+
+	Feed      string // Name of the feed: "URLhaus", "PhishTank" or "OpenPhish"
+	Source    string // Name of the file or reader the entry came from
+	Line      int    // Line of the entry in Source, 0 for JSON arrays and objects
+	ID        string // The feed's identifier of the entry, if it has one
+	Reference string // The entry's page on the feed's site, if it has one
+
+	Threat string   // Kind of threat, e.g. "malware_download" or "phishing"
+	Target string   // Brand targeted by a phishing URL, e.g. "PayPal"
+	Tags   []string // Tags of the entry, e.g. "elf" or "Mozi"
+	Status string   // "online" or "offline" when the feed tracks it
+
+	Added   time.Time // When the feed listed the URL, zero if unknown
+	Updated time.Time // When the feed last checked it, e.g. URLhaus last_online or PhishTank verification_time
+}
+
+// FeedMatchKind tells how a URL matched a feed entry.
+type FeedMatchKind int
+
+const (
+	FeedMatchURL    FeedMatchKind = iota // The URL itself is listed
+	FeedMatchHost                        // A URL on the same host is listed
+	FeedMatchDomain                      // A URL on the same registrable domain is listed
+)
+
+func (k FeedMatchKind) String() string {
+	switch k {
+	case FeedMatchURL:
+		return "URL"
+	case FeedMatchHost:
+		return "Host"
+	case FeedMatchDomain:
+		return "Domain"
+	default:
+		return "Unknown"
+	}
+}
+
+// FeedMatch is a feed entry matching a URL.
+type FeedMatch struct {
+	Kind  FeedMatchKind
+	Entry *FeedEntry
+}
+
+// FeedIndex indexes the URLs of threat feeds by exact URL, host and registrable
+// domain. URLs are validated with ValidateDomain on loading and lookup, and their
+// hosts normalized like NormalizeURI: the scheme and host are lowercased,
+// internationalized hosts converted to Punycode, and the fragment, userinfo and
+// default ports dropped, so "HTTP://Evil.Example.com:80/x#a" matches
+// "http://evil.example.com/x". The zero value is empty; Lookup may be called
+// concurrently once loading is done.
+type FeedIndex struct {
+	urls, hosts, domains map[string][]*FeedEntry
+	count, skipped       int
+}
+
+// WithFeedIndex makes the Extractor look up every match it returns in x, reporting
+// the matching feed entries in Match.FeedMatches.
+func WithFeedIndex(x *FeedIndex) ExtractorOption {
+	return func(e *Extractor) {
+		e.feeds = x
+	}
+}
+
+// LoadFeedIndex loads a FeedIndex from the given feed dumps, all in the same format.
+func LoadFeedIndex(format FeedFormat, paths ...string) (*FeedIndex, error) {
+	x := &FeedIndex{}
+	for _, path := range paths {
+		if err := x.LoadFile(path, format); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// LoadFile loads the entries of a feed dump, reporting path as their Source.
+func (x *FeedIndex) LoadFile(path string, format FeedFormat) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return x.Load(f, path, format)
+}
+
+// Load loads the entries of a feed dump read from r, reporting source as their Source.
+// Entries whose URL isn't valid are skipped and counted by Skipped.
+func (x *FeedIndex) Load(r io.Reader, source string, format FeedFormat) error {
+	var entries []FeedEntry
+	var err error
+	switch format {
+	case FeedURLhausCSV, FeedPhishTankCSV:
+		entries, err = parseFeedCSV(r, format)
+	case FeedURLhausJSON:
+		entries, err = parseURLhausJSON(r)
+	case FeedPhishTankJSON:
+		entries, err = parsePhishTankJSON(r)
+	case FeedOpenPhish:
+		if entries, err = parseFeedText(r, source); err != nil {
+			return err
+		}
+	case FeedOpenPhishJSON:
+		entries, err = parseOpenPhishJSON(r)
+	default:
+		return fmt.Errorf("unknown feed format %d", format)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+
+	for _, entry := range entries {
+		entry.Feed, entry.Source = format.feed(), source
+		if err := x.Add(entry); err != nil {
+			x.skipped++
+		}
+	}
+	return nil
+}
+
+// Add indexes an entry under its URL, filling in Key, Host and Domain.
+func (x *FeedIndex) Add(entry FeedEntry) error {
+	key, host, domain, err := feedKeys(entry.URL)
+	if err != nil {
+		return fmt.Errorf("feed URL %q: %w", entry.URL, err)
+	}
+	entry.Key, entry.Host, entry.Domain = key, host, domain
+
+	if x.urls == nil {
+		x.urls = make(map[string][]*FeedEntry)
+		x.hosts = make(map[string][]*FeedEntry)
+		x.domains = make(map[string][]*FeedEntry)
+	}
+	e := &entry
+	x.urls[key] = append(x.urls[key], e)
+	x.hosts[host] = append(x.hosts[host], e)
+	if domain != "" {
+		x.domains[domain] = append(x.domains[domain], e)
+	}
+	x.count++
+	return nil
+}
+
+// Len returns the number of entries indexed.
+func (x *FeedIndex) Len() int {
+	return x.count
+}
+
+// Skipped returns the number of entries skipped because their URL isn't valid.
+func (x *FeedIndex) Skipped() int {
+	return x.skipped
+}
+
+// Lookup returns the entries matching a URL or domain, those listing the URL first,
+// then those on its host and those on its registrable domain. Each entry is reported
+// once, with its most specific kind of match. A bare domain such as "evil.example"
+// matches as the URL "http://evil.example/".
+func (x *FeedIndex) Lookup(raw string) []FeedMatch {
+	if x.count == 0 {
+		return nil
+	}
+	key, host, domain, err := feedKeys(raw)
+	if err != nil {
+		return nil
+	}
+
+	var matches []FeedMatch
+	seen := make(map[*FeedEntry]bool)
+	add := func(kind FeedMatchKind, entries []*FeedEntry) {
+		for _, e := range entries {
+			if !seen[e] {
+				seen[e] = true
+				matches = append(matches, FeedMatch{Kind: kind, Entry: e})
+			}
+		}
+	}
+	add(FeedMatchURL, x.urls[key])
+	add(FeedMatchHost, x.hosts[host])
+	if domain != "" {
+		add(FeedMatchDomain, x.domains[domain])
+	}
+	return matches
+}
+
+// feedKeys returns the exact-match key, host and registrable domain of a URL.
+func feedKeys(raw string) (key, host, domain string, err error) {
+	result := ValidateDomain(strings.TrimSpace(raw))
+	if !result.Valid {
+		return "", "", "", errors.New(result.Reason)
+	}
+
+	u := result.URL
+	authority := result.ASCIIHost
+	if result.Type == URLTypeIP {
+		host = result.TLD
+		authority = host
+		if addr, err := netip.ParseAddr(host); err == nil && addr.Is6() {
+			authority = "[" + host + "]"
+		}
+	} else {
+		host, domain = result.ASCIIHost, site(result.ASCIIHost)
+	}
+	if result.Port != 0 && !result.DefaultPort {
+		authority += ":" + strconv.Itoa(result.Port)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key = scheme + "://" + authority + path
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key, host, domain, nil
+}
+
+// feedColumns are the columns of the CSV dumps, in the order of the files.
+var feedColumns = map[FeedFormat][]string{
+	FeedURLhausCSV:   {"id", "dateadded", "url", "url_status", "last_online", "threat", "tags", "urlhaus_link", "reporter"},
+	FeedPhishTankCSV: {"phish_id", "url", "phish_detail_url", "submission_time", "verified", "verification_time", "online", "target"},
+}
+
+// parseFeedCSV parses a URLhaus or PhishTank CSV dump. A header row, which URLhaus
+// dumps have as a comment, may reorder the columns.
+func parseFeedCSV(r io.Reader, format FeedFormat) ([]FeedEntry, error) {
+	columns := make(map[string]int)
+	for i, name := range feedColumns[format] {
+		columns[name] = i
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+
+	var entries []FeedEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record[0] == feedColumns[format][0] {
+			clear(columns)
+			for i, name := range record {
+				columns[strings.TrimSpace(name)] = i
+			}
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		entry := FeedEntry{URL: field(record, "url"), Line: line}
+		switch format {
+		case FeedURLhausCSV:
+			entry.ID, entry.Reference = field(record, "id"), field(record, "urlhaus_link")
+			entry.Threat, entry.Status = field(record, "threat"), field(record, "url_status")
+			entry.Tags = splitTags(field(record, "tags"))
+			entry.Added = parseFeedTime(field(record, "dateadded"))
+			entry.Updated = parseFeedTime(field(record, "last_online"))
+		case FeedPhishTankCSV:
+			entry.ID, entry.Reference = field(record, "phish_id"), field(record, "phish_detail_url")
+			entry.Threat, entry.Target = "phishing", field(record, "target")
+			entry.Status = phishTankStatus(field(record, "online"))
+			entry.Added = parseFeedTime(field(record, "submission_time"))
+			entry.Updated = parseFeedTime(field(record, "verification_time"))
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func parseURLhausJSON(r io.Reader) ([]FeedEntry, error) {
+	var dump map[string][]struct {
+		DateAdded   string   `json:"dateadded"`
+		URL         string   `json:"url"`
+		URLStatus   string   `json:"url_status"`
+		LastOnline  string   `json:"last_online"`
+		Threat      string   `json:"threat"`
+		Tags        []string `json:"tags"`
+		URLhausLink string   `json:"urlhaus_link"`
+	}
+	if err := json.NewDecoder(r).Decode(&dump); err != nil {
+		return nil, err
+	}
+
+	var entries []FeedEntry
+	for _, id := range slices.Sorted(maps.Keys(dump)) {
+		for _, item := range dump[id] {
+			entries = append(entries, FeedEntry{
+				URL:       item.URL,
+				ID:        id,
+				Reference: item.URLhausLink,
+				Threat:    item.Threat,
+				Tags:      item.Tags,
+				Status:    item.URLStatus,
+				Added:     parseFeedTime(item.DateAdded),
+				Updated:   parseFeedTime(item.LastOnline),
+			})
+		}
+	}
+	return entries, nil
+}
+
+func parsePhishTankJSON(r io.Reader) ([]FeedEntry, error) {
+	var dump []struct {
+		PhishID          json.Number `json:"phish_id"`
+		URL              string      `json:"url"`
+		PhishDetailURL   string      `json:"phish_detail_url"`
+		SubmissionTime   string      `json:"submission_time"`
+		VerificationTime string      `json:"verification_time"`
+		Online           string      `json:"online"`
+		Target           string      `json:"target"`
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&dump); err != nil {
+		return nil, err
+	}
+
+	entries := make([]FeedEntry, 0, len(dump))
+	for _, item := range dump {
+		entries = append(entries, FeedEntry{
+			URL:       item.URL,
+			ID:        item.PhishID.String(),
+			Reference: item.PhishDetailURL,
+			Threat:    "phishing",
+			Target:    item.Target,
+			Status:    phishTankStatus(item.Online),
+			Added:     parseFeedTime(item.SubmissionTime),
+			Updated:   parseFeedTime(item.VerificationTime),
+		})
+	}
+	return entries, nil
+}
+
+// parseFeedText parses a feed with one URL per line and '#' comment lines. Comments
+// aren't cut from the end of lines, where '#' starts the fragment of the URL.
+func parseFeedText(r io.Reader, source string) ([]FeedEntry, error) {
+	var entries []FeedEntry
+	err := scanLines(r, source, func(text string, line int) error {
+		if text != "" && text[0] != '#' {
+			entries = append(entries, FeedEntry{URL: text, Line: line, Threat: "phishing"})
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// openPhishEntry holds the fields of the OpenPhish JSON feeds used for entries.
+type openPhishEntry struct {
+	URL          string `json:"url"`
+	Brand        string `json:"brand"`
+	Sector       string `json:"sector"`
+	DiscoverTime string `json:"discover_time"`
+	ISOTime      string `json:"isotime"`
+}
+
+func parseOpenPhishJSON(r io.Reader) ([]FeedEntry, error) {
+	var items []openPhishEntry
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(string(raw), "[") {
+			var batch []openPhishEntry
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return nil, err
+			}
+			items = append(items, batch...)
+			continue
+		}
+		var item openPhishEntry
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	entries := make([]FeedEntry, 0, len(items))
+	for _, item := range items {
+		added := parseFeedTime(item.ISOTime)
+		if added.IsZero() {
+			added = parseFeedTime(item.DiscoverTime)
+		}
+		entry := FeedEntry{URL: item.URL, Threat: "phishing", Target: item.Brand, Added: added}
+		if item.Sector != "" {
+			entry.Tags = []string{item.Sector}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// feedTimeLayouts are the timestamp layouts of the feeds, all in UTC unless stated.
+var feedTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+func parseFeedTime(s string) time.Time {
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" && tag != "None" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func phishTankStatus(online string) string {
+	switch online {
+	case "yes":
+		return "online"
+	case "no":
+		return "offline"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,231 @@
+package urlverify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testURLhausCSV = `################################################################
+# abuse.ch URLhaus Database Dump (CSV)                          #
+################################################################
+#
+# id,dateadded,url,url_status,last_online,threat,tags,urlhaus_link,reporter
+"3111234","2024-07-01 12:00:05","http://198.51.100.7:4000/i","online","2024-07-02 08:30:00","malware_download","32-bit,elf,Mozi","https://urlhaus.abuse.ch/url/3111234/","geenensp"
+"3111233","2024-07-01 11:59:00","https://Cdn.Evil.Example.com/payload.exe#x","offline","","malware_download","None","https://urlhaus.abuse.ch/url/3111233/","abuse_ch"
+"3111232","2024-07-01 11:58:00","not a url","offline","","malware_download","None","https://urlhaus.abuse.ch/url/3111232/","abuse_ch"
+`
+
+const testURLhausJSON = `{
+	"3111234": [{"dateadded": "2024-07-01 12:00:05 UTC", "url": "http://198.51.100.7:4000/i", "url_status": "online",
+		"last_online": "2024-07-02 08:30:00 UTC", "threat": "malware_download", "tags": ["elf", "Mozi"],
+		"urlhaus_link": "https://urlhaus.abuse.ch/url/3111234/", "reporter": "geenensp"}],
+	"3111233": [{"dateadded": "2024-07-01 11:59:00 UTC", "url": "https://cdn.evil.example.com/payload.exe",
+		"url_status": "offline", "last_online": null, "threat": "malware_download", "tags": null,
+		"urlhaus_link": "https://urlhaus.abuse.ch/url/3111233/", "reporter": "abuse_ch"}]
+}`
+
+const testPhishTankCSV = `phish_id,url,phish_detail_url,submission_time,verified,verification_time,online,target
+8612345,https://login.example-bank.com/verify?acct=1,http://www.phishtank.com/phish_detail.php?phish_id=8612345,2024-07-01T10:00:00+00:00,yes,2024-07-01T10:05:00+00:00,yes,Example Bank
+8612346,"http://paypal.account-check.example.net/",http://www.phishtank.com/phish_detail.php?phish_id=8612346,2024-07-01T11:00:00+00:00,yes,2024-07-01T11:20:00+00:00,no,PayPal
+`
+
+const testPhishTankJSON = `[
+	{"phish_id": 8612345, "url": "https://login.example-bank.com/verify?acct=1",
+	 "phish_detail_url": "http://www.phishtank.com/phish_detail.php?phish_id=8612345",
+	 "submission_time": "2024-07-01T10:00:00+00:00", "verified": "yes",
+	 "verification_time": "2024-07-01T10:05:00+00:00", "online": "yes", "details": [], "target": "Example Bank"}
+]`
+
+const testOpenPhish = `https://secure-login.example.org/office365/
+http://xn--e1afmkfd.example.com/
+`
+
+const testOpenPhishJSON = `{"url": "https://secure-login.example.org/office365/", "brand": "Microsoft", "sector": "Technology", "isotime": "2024-07-01T09:00:00Z"}
+{"url": "https://wallet.example.io/", "brand": "MetaMask", "discover_time": "2024-07-01 09:30:00"}
+`
+
+func TestFeedIndexURLhaus(t *testing.T) {
+	var x FeedIndex
+	if err := x.Load(strings.NewReader(testURLhausCSV), "csv.txt", FeedURLhausCSV); err != nil {
+		t.Fatal(err)
+	}
+	if x.Len() != 2 || x.Skipped() != 1 {
+		t.Errorf("Len() = %d, Skipped() = %d, want 2, 1", x.Len(), x.Skipped())
+	}
+
+	matches := x.Lookup("http://198.51.100.7:4000/i")
+	if len(matches) != 1 || matches[0].Kind != FeedMatchURL {
+		t.Fatalf("Lookup(IP URL) = %+v", matches)
+	}
+	want := FeedEntry{
+		URL: "http://198.51.100.7:4000/i", Key: "http://198.51.100.7:4000/i", Host: "198.51.100.7",
+		Feed: "URLhaus", Source: "csv.txt", Line: 6, ID: "3111234", Reference: "https://urlhaus.abuse.ch/url/3111234/",
+		Threat: "malware_download", Tags: []string{"32-bit", "elf", "Mozi"}, Status: "online",
+		Added:   time.Date(2024, 7, 1, 12, 0, 5, 0, time.UTC),
+		Updated: time.Date(2024, 7, 2, 8, 30, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(*matches[0].Entry, want) {
+		t.Errorf("entry = %+v\nwant %+v", *matches[0].Entry, want)
+	}
+
+	entry := x.Lookup("https://cdn.evil.example.com/payload.exe")[0].Entry
+	if entry.Key != "https://cdn.evil.example.com/payload.exe" || entry.Domain != "example.com" || entry.Tags != nil || !entry.Updated.IsZero() {
+		t.Errorf("normalized entry = %+v", entry)
+	}
+
+	json, err := os.CreateTemp(t.TempDir(), "json.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.WriteString(testURLhausJSON)
+	json.Close()
+	fromJSON, err := LoadFeedIndex(FeedURLhausJSON, json.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := fromJSON.Lookup("198.51.100.7:4000/i")
+	if len(m) != 1 || m[0].Entry.ID != "3111234" || !m[0].Entry.Added.Equal(want.Added) || !reflect.DeepEqual(m[0].Entry.Tags, []string{"elf", "Mozi"}) {
+		t.Errorf("Lookup(JSON) = %+v", m)
+	}
+}
+
+func TestFeedIndexMatchKinds(t *testing.T) {
+	var x FeedIndex
+	x.Load(strings.NewReader(testURLhausCSV), "csv.txt", FeedURLhausCSV)
+	x.Load(strings.NewReader(testPhishTankCSV), "online-valid.csv", FeedPhishTankCSV)
+
+	tests := []struct {
+		input string
+		kinds []FeedMatchKind
+	}{
+		{"HTTPS://CDN.evil.example.com:443/payload.exe", []FeedMatchKind{FeedMatchURL}},
+		{"https://user@cdn.evil.example.com/payload.exe#top", []FeedMatchKind{FeedMatchURL}},
+		{"https://cdn.evil.example.com/other", []FeedMatchKind{FeedMatchHost}},
+		{"www.example.com", []FeedMatchKind{FeedMatchDomain}},
+		{"http://198.51.100.7/", []FeedMatchKind{FeedMatchHost}},
+		{"https://login.example-bank.com/verify?acct=1", []FeedMatchKind{FeedMatchURL}},
+		{"https://login.example-bank.com/verify?acct=2", []FeedMatchKind{FeedMatchHost}},
+		{"https://example.org/", nil},
+		{"not a url", nil},
+	}
+	for _, tt := range tests {
+		var kinds []FeedMatchKind
+		for _, m := range x.Lookup(tt.input) {
+			kinds = append(kinds, m.Kind)
+		}
+		if !reflect.DeepEqual(kinds, tt.kinds) {
+			t.Errorf("Lookup(%q) kinds = %v, want %v", tt.input, kinds, tt.kinds)
+		}
+	}
+}
+
+func TestFeedIndexPhishTank(t *testing.T) {
+	var csvIndex, jsonIndex FeedIndex
+	if err := csvIndex.Load(strings.NewReader(testPhishTankCSV), "online-valid.csv", FeedPhishTankCSV); err != nil {
+		t.Fatal(err)
+	}
+	if err := jsonIndex.Load(strings.NewReader(testPhishTankJSON), "online-valid.json", FeedPhishTankJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, x := range []*FeedIndex{&csvIndex, &jsonIndex} {
+		m := x.Lookup("https://login.example-bank.com/verify?acct=1")
+		if len(m) != 1 {
+			t.Fatalf("Lookup() = %+v", m)
+		}
+		e := m[0].Entry
+		if e.Feed != "PhishTank" || e.ID != "8612345" || e.Target != "Example Bank" || e.Status != "online" || e.Threat != "phishing" ||
+			!e.Added.Equal(time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)) || !e.Updated.Equal(time.Date(2024, 7, 1, 10, 5, 0, 0, time.UTC)) {
+			t.Errorf("%s entry = %+v", e.Source, e)
+		}
+	}
+	if m := csvIndex.Lookup("http://paypal.account-check.example.net/"); len(m) != 1 || m[0].Entry.Status != "offline" || m[0].Entry.Line != 3 {
+		t.Errorf("Lookup(offline) = %+v", m)
+	}
+}
+
+func TestFeedIndexOpenPhish(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "feed.txt"), []byte(testOpenPhish), 0o644)
+	os.WriteFile(filepath.Join(dir, "feed.json"), []byte(testOpenPhishJSON), 0o644)
+
+	text, err := LoadFeedIndex(FeedOpenPhish, filepath.Join(dir, "feed.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := text.Lookup("https://secure-login.example.org/office365/"); len(m) != 1 || m[0].Entry.Feed != "OpenPhish" || m[0].Entry.Line != 1 {
+		t.Errorf("Lookup(text) = %+v", m)
+	}
+	// Internationalized hosts match in either form
+	if m := text.Lookup("http://пример.example.com/"); len(m) != 1 || m[0].Kind != FeedMatchURL {
+		t.Errorf("Lookup(IDN) = %+v", m)
+	}
+
+	js, err := LoadFeedIndex(FeedOpenPhishJSON, filepath.Join(dir, "feed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if js.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", js.Len())
+	}
+	m := js.Lookup("https://wallet.example.io/")
+	if len(m) != 1 || m[0].Entry.Target != "MetaMask" || !m[0].Entry.Added.Equal(time.Date(2024, 7, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("Lookup(JSON) = %+v", m)
+	}
+
+	array, err := os.ReadFile(filepath.Join(dir, "feed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromArray FeedIndex
+	arrayJSON := "[" + strings.Replace(strings.TrimSpace(string(array)), "\n", ",", 1) + "]"
+	if err := fromArray.Load(strings.NewReader(arrayJSON), "feed.json", FeedOpenPhishJSON); err != nil || fromArray.Len() != 2 {
+		t.Errorf("Load(array) = %v, Len() = %d", err, fromArray.Len())
+	}
+}
+
+func TestFeedIndexErrors(t *testing.T) {
+	var x FeedIndex
+	for format, bad := range map[FeedFormat]string{
+		FeedURLhausJSON:   `["not", "an", "object"]`,
+		FeedPhishTankJSON: `{"phish_id": 1}`,
+		FeedOpenPhishJSON: `{"url": `,
+		FeedPhishTankCSV:  "phish_id,url\n1,\"http://a.example.com/\"x\"y\n\"",
+	} {
+		if err := x.Load(strings.NewReader(bad), "bad", format); err == nil || !strings.HasPrefix(err.Error(), "bad: ") {
+			t.Errorf("Load(%v) error = %v", format, err)
+		}
+	}
+	if err := x.Load(strings.NewReader(""), "bad", FeedFormat(42)); err == nil {
+		t.Error("Load() with an unknown format succeeded")
+	}
+	if err := x.Add(FeedEntry{URL: "http://-bad-.example.com/"}); err == nil {
+		t.Error("Add() of an invalid URL succeeded")
+	}
+	if _, err := LoadFeedIndex(FeedOpenPhish, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadFeedIndex of a missing file succeeded")
+	}
+	if FeedPhishTankJSON.String() != "PhishTank JSON" || FeedMatchDomain.String() != "Domain" {
+		t.Errorf("String() = %q, %q", FeedPhishTankJSON, FeedMatchDomain)
+	}
+}
+
+func TestExtractorFeedIndex(t *testing.T) {
+	var x FeedIndex
+	x.Load(strings.NewReader(testURLhausCSV), "csv.txt", FeedURLhausCSV)
+	text := "Dropper at https://cdn.evil.example.com/payload.exe, docs on example.org"
+	matches := NewExtractor(WithFeedIndex(&x)).FindAll(text)
+	if len(matches) != 2 {
+		t.Fatalf("FindAll() returned %d matches, want 2", len(matches))
+	}
+	if got := matches[0].FeedMatches; len(got) != 1 || got[0].Kind != FeedMatchURL || got[0].Entry.ID != "3111233" {
+		t.Errorf("%s feed matches = %+v", matches[0].Text, got)
+	}
+	if got := matches[1].FeedMatches; got != nil {
+		t.Errorf("%s feed matches = %+v, want none", matches[1].Text, got)
+	}
+}
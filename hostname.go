@@ -0,0 +1,150 @@
+package urlverify
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// ReasonCode identifies why a URL or domain was accepted or rejected.
+// ValidationResult.Reason holds the matching human-readable explanation.
+type ReasonCode int
+
+const (
+	ReasonValid            ReasonCode = iota // The URL or domain is valid
+	ReasonParseError                         // The URL couldn't be parsed
+	ReasonEmptyHost                          // The URL has no host
+	ReasonIDNA                               // The IDNA conversion failed, see ValidationResult.IDNARule
+	ReasonNoTLD                              // The host has no dot or no public suffix
+	ReasonInvalidTLD                         // The TLD is unknown or not an ICANN TLD
+	ReasonNumericTLD                         // The TLD is all digits (RFC 1123 section 2.1)
+	ReasonNameTooLong                        // The name exceeds 253 octets (RFC 1035 section 2.3.4)
+	ReasonLabelTooLong                       // A label exceeds 63 octets (RFC 1035 section 2.3.4)
+	ReasonEmptyLabel                         // The name contains consecutive dots or starts with one
+	ReasonHyphenEdge                         // A label starts or ends with a hyphen (RFC 952, RFC 1123)
+	ReasonReservedHyphens                    // A label other than xn-- has hyphens in positions 3 and 4 (RFC 5891)
+	ReasonInvalidPunycode                    // An xn-- label doesn't decode to a valid U-label (RFC 5891)
+	ReasonInvalidCharacter                   // A label contains a character other than a letter, digit or hyphen
+	ReasonInvalidPort                        // The port is outside 1-65535
+)
+
+func (c ReasonCode) String() string {
+	switch c {
+	case ReasonValid:
+		return "Valid"
+	case ReasonParseError:
+		return "Parse Error"
+	case ReasonEmptyHost:
+		return "Empty Host"
+	case ReasonIDNA:
+		return "IDNA Error"
+	case ReasonNoTLD:
+		return "No TLD"
+	case ReasonInvalidTLD:
+		return "Invalid TLD"
+	case ReasonNumericTLD:
+		return "Numeric TLD"
+	case ReasonNameTooLong:
+		return "Name Too Long"
+	case ReasonLabelTooLong:
+		return "Label Too Long"
+	case ReasonEmptyLabel:
+		return "Empty Label"
+	case ReasonHyphenEdge:
+		return "Leading or Trailing Hyphen"
+	case ReasonReservedHyphens:
+		return "Reserved Hyphens"
+	case ReasonInvalidPunycode:
+		return "Invalid Punycode"
+	case ReasonInvalidCharacter:
+		return "Invalid Character"
+	case ReasonInvalidPort:
+		return "Invalid Port"
+	default:
+		return "Unknown"
+	}
+}
+
+const (
+	maxNameLength  = 253
+	maxLabelLength = 63
+)
+
+// ValidateDNSName validates a DNS name such as "_dmarc.example.com" or
+// "_sip._tcp.example.com", which unlike host names may contain underscores.
+// See WithDNSNames.
+func ValidateDNSName(name string) ValidationResult {
+	return dnsNameValidator.ValidateDomain(name)
+}
+
+var dnsNameValidator = NewValidator(WithDNSNames())
+
+// checkStructure checks the length and label syntax of a normalized ASCII host.
+// Hyphens in the third and fourth positions are only reserved for registration,
+// hosts such as "r3---sn-abc.googlevideo.com" are in use.
+func (v *Validator) checkStructure(host string) (ReasonCode, string) {
+	if len(host) > maxNameLength {
+		return ReasonNameTooLong, fmt.Sprintf("domain name longer than %d octets", maxNameLength)
+	}
+
+	for rest := host; ; {
+		label, after, more := strings.Cut(rest, ".")
+		switch {
+		case label == "":
+			return ReasonEmptyLabel, "empty label"
+		case len(label) > maxLabelLength:
+			return ReasonLabelTooLong, fmt.Sprintf("label %q longer than %d octets", label, maxLabelLength)
+		case label[0] == '-' || label[len(label)-1] == '-':
+			return ReasonHyphenEdge, fmt.Sprintf("label %q starts or ends with a hyphen", label)
+		case strings.HasPrefix(label, "xn--"):
+			if !isPunycodeLabel(label) {
+				return ReasonInvalidPunycode, fmt.Sprintf("label %q is not valid punycode", label)
+			}
+		case v.profile == IDNARegistration && len(label) >= 4 && label[2:4] == "--":
+			return ReasonReservedHyphens, fmt.Sprintf("label %q has hyphens in the third and fourth positions", label)
+		}
+		if more {
+			rest = after
+			continue
+		}
+
+		if strings.Trim(label, "0123456789") == "" {
+			return ReasonNumericTLD, fmt.Sprintf("numeric TLD %q", label)
+		}
+		return ReasonValid, ""
+	}
+}
+
+// isPunycodeLabel reports whether an xn-- label decodes to a non-ASCII label of
+// printable characters that encodes back to the same A-label.
+func isPunycodeLabel(label string) bool {
+	decoded, err := idna.Punycode.ToUnicode(label)
+	if err != nil || isASCII(decoded) || strings.IndexFunc(decoded, isUnprintable) >= 0 {
+		return false
+	}
+	encoded, err := idna.Punycode.ToASCII(decoded)
+	return err == nil && encoded == label
+}
+
+// isUnprintable reports whether r can't appear in a U-label. The zero width joiner
+// is allowed for emoji sequences.
+func isUnprintable(r rune) bool {
+	return !unicode.IsGraphic(r) && r != '\u200d'
+}
+
+// checkCharacters checks that the labels of a normalized ASCII host only contain
+// letters, digits and hyphens, and also underscores in DNS-name mode.
+func (v *Validator) checkCharacters(host string) (ReasonCode, string) {
+	for i := 0; i < len(host); i++ {
+		c := host[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '.':
+		case c == '_' && v.dnsNames:
+		default:
+			return ReasonInvalidCharacter, fmt.Sprintf("invalid character %q in host name", c)
+		}
+	}
+	return ReasonValid, ""
+}
@@ -0,0 +1,94 @@
+package urlverify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDomainStructure(t *testing.T) {
+	label63 := strings.Repeat("a", 63)
+
+	tests := []struct {
+		input       string
+		code        ReasonCode
+		description string
+	}{
+		{label63 + ".com", ReasonValid, "63 octet label"},
+		{strings.Repeat("a", 64) + ".com", ReasonLabelTooLong, "64 octet label"},
+		{"https://" + strings.Repeat("a", 80) + ".example.com/path", ReasonLabelTooLong, "80 octet label in URL"},
+		{strings.Repeat(label63+".", 3) + strings.Repeat("a", 57) + ".com", ReasonValid, "name of 253 octets"},
+		{strings.Repeat(label63+".", 4) + "com", ReasonNameTooLong, "name over 253 octets"},
+		{"-example.com", ReasonHyphenEdge, "leading hyphen"},
+		{"example-.com", ReasonHyphenEdge, "trailing hyphen"},
+		{"sub.-example.com", ReasonHyphenEdge, "leading hyphen in subdomain"},
+		{"ex-ample.com", ReasonValid, "inner hyphen"},
+		{"ab--cd.com", ReasonValid, "hyphens in positions 3 and 4"},
+		{"r3---sn-abc.googlevideo.com", ReasonValid, "hyphens in positions 3 to 5"},
+		{"example.com.", ReasonValid, "fully qualified name"},
+		{"https://example.com./path", ReasonValid, "fully qualified name in URL"},
+		{"example.com..", ReasonEmptyLabel, "two trailing dots"},
+		{"xn--80afohp.xn--p1ai", ReasonValid, "valid punycode"},
+		{"xn--ls8h.com", ReasonValid, "emoji punycode"},
+		{"xn--abcdefg.com", ReasonInvalidPunycode, "punycode that decodes to control characters"},
+		{"xn--zz.com", ReasonInvalidPunycode, "malformed punycode"},
+		{"example.123", ReasonNumericTLD, "numeric TLD"},
+		{"a..example.com", ReasonEmptyLabel, "empty label"},
+		{"exa$mple.com", ReasonInvalidCharacter, "dollar sign"},
+		{"_dmarc.example.com", ReasonInvalidCharacter, "underscore in host name"},
+		{"not_a_valid_domain.dse", ReasonInvalidTLD, "unknown TLD reported before characters"},
+		{"example", ReasonNoTLD, "no dot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			result := ValidateDomain(tt.input)
+			if result.Code != tt.code {
+				t.Errorf("ValidateDomain(%q) code = %v, want %v (%s)", tt.input, result.Code, tt.code, result.Reason)
+			}
+			if result.Valid != (tt.code == ReasonValid) {
+				t.Errorf("ValidateDomain(%q) valid = %v, want %v", tt.input, result.Valid, tt.code == ReasonValid)
+			}
+		})
+	}
+}
+
+func TestValidateDNSName(t *testing.T) {
+	tests := []struct {
+		input string
+		code  ReasonCode
+	}{
+		{"_dmarc.example.com", ReasonValid},
+		{"_sip._tcp.example.com", ReasonValid},
+		{"selector1._domainkey.example.org", ReasonValid},
+		{"_443._tcp.mail.example.net", ReasonValid},
+		{"_dmarc.-example.com", ReasonHyphenEdge},
+		{"_dmarc.example.123", ReasonNumericTLD},
+		{"exa$mple.com", ReasonInvalidCharacter},
+	}
+
+	for _, tt := range tests {
+		result := ValidateDNSName(tt.input)
+		if result.Code != tt.code {
+			t.Errorf("ValidateDNSName(%q) code = %v, want %v (%s)", tt.input, result.Code, tt.code, result.Reason)
+		}
+	}
+}
+
+func TestExtractUnderscoreLabels(t *testing.T) {
+	text := "Publish _dmarc.example.com and sub_domain.example.com, then check www.example.com"
+
+	if got := ExtractAll(text); len(got) != 1 || got[0] != "www.example.com" {
+		t.Errorf("ExtractAll() = %q, want [www.example.com]", got)
+	}
+
+	got := NewExtractor(WithValidator(NewValidator(WithDNSNames()))).FindAll(text)
+	want := []string{"_dmarc.example.com", "sub_domain.example.com", "www.example.com"}
+	if len(got) != len(want) {
+		t.Fatalf("FindAll() with DNS names returned %d matches, want %d: %v", len(got), len(want), got)
+	}
+	for i, m := range got {
+		if m.Text != want[i] {
+			t.Errorf("FindAll() result[%d] = %q, want %q", i, m.Text, want[i])
+		}
+	}
+}
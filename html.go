@@ -0,0 +1,244 @@
+package urlverify
+
+import (
+	"html"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// htmlURLAttrs lists the attributes holding URLs for each tag.
+var htmlURLAttrs = map[string][]string{
+	"a":          {"href"},
+	"area":       {"href"},
+	"link":       {"href"},
+	"base":       {"href"},
+	"img":        {"src", "srcset", "longdesc"},
+	"source":     {"src", "srcset"},
+	"script":     {"src"},
+	"iframe":     {"src"},
+	"frame":      {"src", "longdesc"},
+	"embed":      {"src"},
+	"audio":      {"src"},
+	"video":      {"src", "poster"},
+	"track":      {"src"},
+	"object":     {"data"},
+	"form":       {"action"},
+	"button":     {"formaction"},
+	"input":      {"formaction", "src"},
+	"blockquote": {"cite"},
+	"q":          {"cite"},
+	"del":        {"cite"},
+	"ins":        {"cite"},
+	"body":       {"background"},
+	"table":      {"background"},
+	"td":         {"background"},
+}
+
+// HTMLLink represents a valid URL found in an HTML document.
+// The embedded Match holds the URL with character references decoded; Start and End
+// point at the attribute value or text in the source, or at the whole tag if the
+// value can't be located verbatim.
+type HTMLLink struct {
+	Match
+	Tag      string // Tag the URL was found in, empty for URLs in text
+	Attr     string // Attribute the URL was found in, empty for URLs in text
+	LinkText string // Text content of the enclosing <a> element for href attributes
+}
+
+// ExtractHTML extracts all valid URLs from an HTML document. URLs are taken from link,
+// media and form attributes, meta refresh tags and from visible text; script and style
+// contents are ignored. Relative URLs and non-web schemes such as mailto: are skipped.
+func ExtractHTML(text string) []HTMLLink {
+	var (
+		links    []HTMLLink
+		offset   int
+		skipText string
+		anchor   = -1
+		anchorTx strings.Builder
+		z        = nethtml.NewTokenizer(strings.NewReader(text))
+	)
+
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		raw := string(z.Raw())
+		start := offset
+		offset += len(raw)
+
+		switch tt {
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			attrs := htmlURLAttrs[tag]
+			isRefresh := false
+			var content string
+
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				attr := string(key)
+				if tag == "meta" {
+					if attr == "http-equiv" && strings.EqualFold(string(val), "refresh") {
+						isRefresh = true
+					}
+					if attr == "content" {
+						content = string(val)
+					}
+					continue
+				}
+				for _, a := range attrs {
+					if a == attr {
+						links = appendHTMLAttr(links, raw, start, tag, attr, string(val))
+					}
+				}
+			}
+
+			if isRefresh {
+				if i := strings.Index(strings.ToLower(content), "url="); i >= 0 {
+					dest := strings.Trim(strings.TrimSpace(content[i+4:]), `'"`)
+					links = appendHTMLValue(links, raw, start, tag, "content", dest)
+				}
+			}
+
+			switch {
+			case tag == "script" || tag == "style":
+				if tt == nethtml.StartTagToken {
+					skipText = tag
+				}
+			case tag == "a" && tt == nethtml.StartTagToken:
+				anchor = len(links) - 1
+				if anchor >= 0 && (links[anchor].Tag != "a" || links[anchor].Start < start) {
+					anchor = -1
+				}
+				anchorTx.Reset()
+			}
+
+		case nethtml.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if tag == skipText {
+				skipText = ""
+			}
+			if tag == "a" {
+				if anchor >= 0 {
+					links[anchor].LinkText = strings.Join(strings.Fields(anchorTx.String()), " ")
+				}
+				anchor = -1
+			}
+
+		case nethtml.TextToken:
+			if skipText != "" {
+				continue
+			}
+			text, offsets := unescapeHTMLText(raw)
+			if anchor >= 0 {
+				anchorTx.WriteString(text)
+			}
+			for _, m := range FindAll(text) {
+				m.Start, m.End = start+offsets[m.Start], start+offsets[m.End]
+				links = append(links, HTMLLink{Match: m})
+			}
+		}
+	}
+
+	return links
+}
+
+// unescapeHTMLText decodes the character references in the text of a text node.
+// offsets maps every byte of the decoded string, and the end of it, to the
+// corresponding position in raw.
+func unescapeHTMLText(raw string) (string, []int) {
+	if !strings.Contains(raw, "&") {
+		offsets := make([]int, len(raw)+1)
+		for i := range offsets {
+			offsets[i] = i
+		}
+		return raw, offsets
+	}
+
+	var (
+		b       strings.Builder
+		offsets []int
+	)
+	for i := 0; i < len(raw); {
+		j := strings.IndexByte(raw[i+1:], '&') + i + 1
+		if raw[i] == '&' {
+			// A reference decodes the same on its own as in the text around it, it ends
+			// at the first character that can't be part of its name or number
+			j = i + 1
+			for j < len(raw) && (isASCIIAlnum(raw[j]) || raw[j] == '#') {
+				j++
+			}
+			if j < len(raw) && raw[j] == ';' {
+				j++
+			}
+		} else if j == i {
+			j = len(raw)
+		}
+		piece := raw[i:j]
+		decoded := html.UnescapeString(piece)
+
+		// Text following a reference without ';', as in "&ampx", stays as it was
+		same := len(piece)
+		if decoded != piece {
+			same = 0
+			for same < len(decoded)-1 && same < len(piece)-1 && decoded[len(decoded)-1-same] == piece[len(piece)-1-same] {
+				same++
+			}
+		}
+		for k := 0; k < len(decoded)-same; k++ {
+			offsets = append(offsets, i)
+		}
+		for k := len(piece) - same; k < len(piece); k++ {
+			offsets = append(offsets, i+k)
+		}
+		b.WriteString(decoded)
+		i = j
+	}
+
+	return b.String(), append(offsets, len(raw))
+}
+
+func isASCIIAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func appendHTMLAttr(links []HTMLLink, raw string, start int, tag, attr, val string) []HTMLLink {
+	if attr != "srcset" {
+		return appendHTMLValue(links, raw, start, tag, attr, strings.TrimSpace(val))
+	}
+
+	// srcset is a comma separated list of "url [descriptor]" candidates
+	for _, candidate := range strings.Split(val, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			links = appendHTMLValue(links, raw, start, tag, attr, fields[0])
+		}
+	}
+	return links
+}
+
+func appendHTMLValue(links []HTMLLink, raw string, start int, tag, attr, val string) []HTMLLink {
+	if !isWebDestination(val) {
+		return links
+	}
+
+	result := ValidateDomain(val)
+	if !result.Valid {
+		return links
+	}
+
+	m := Match{
+		Text:   val,
+		Start:  start,
+		End:    start + len(raw),
+		Result: result,
+	}
+	if i := strings.Index(raw, val); i >= 0 {
+		m.Start, m.End = start+i, start+i+len(val)
+	}
+
+	return append(links, HTMLLink{Match: m, Tag: tag, Attr: attr})
+}
@@ -0,0 +1,75 @@
+package urlverify
+
+import (
+	"testing"
+)
+
+func TestExtractHTML(t *testing.T) {
+	text := `<html><head>
+<meta http-equiv="refresh" content="5; url=https://refresh.example.com/next">
+<link rel="stylesheet" href="/static/site.css">
+<script src="https://cdn.example.org/app.js">var u = "https://script.example.com";</script>
+<style>body { background: url(https://style.example.com/bg.png) }</style>
+</head><body>
+<p>Visit <a href="https://example.com/login?a=1&amp;b=2">our <b>login</b> page</a> or github.com.</p>
+<img src="https://img.example.com/a.png" srcset="https://img.example.com/a-2x.png 2x, /local.png 3x">
+<a href="mailto:abuse@example.com">abuse</a>
+</body></html>`
+
+	expected := []struct {
+		text     string
+		tag      string
+		attr     string
+		linkText string
+	}{
+		{"https://refresh.example.com/next", "meta", "content", ""},
+		{"https://cdn.example.org/app.js", "script", "src", ""},
+		{"https://example.com/login?a=1&b=2", "a", "href", "our login page"},
+		{"github.com", "", "", ""},
+		{"https://img.example.com/a.png", "img", "src", ""},
+		{"https://img.example.com/a-2x.png", "img", "srcset", ""},
+	}
+
+	result := ExtractHTML(text)
+
+	if len(result) != len(expected) {
+		t.Errorf("ExtractHTML() returned %d links, want %d", len(result), len(expected))
+		for _, l := range result {
+			t.Logf("Got: %s %s %q", l.Tag, l.Attr, l.Text)
+		}
+		return
+	}
+
+	for i, want := range expected {
+		got := result[i]
+		if got.Text != want.text || got.Tag != want.tag || got.Attr != want.attr || got.LinkText != want.linkText {
+			t.Errorf("ExtractHTML() result[%d] = {%q %q %q %q}, want {%q %q %q %q}",
+				i, got.Text, got.Tag, got.Attr, got.LinkText, want.text, want.tag, want.attr, want.linkText)
+		}
+	}
+}
+
+func TestExtractHTMLTextReferences(t *testing.T) {
+	text := `<p>See https://example.com/q?a=1&amp;b=2 and example&#46;org.</p>`
+
+	expected := []struct {
+		text string
+		host string
+		span string
+	}{
+		{"https://example.com/q?a=1&b=2", "example.com", "https://example.com/q?a=1&amp;b=2"},
+		{"example.org", "example.org", "example&#46;org"},
+	}
+
+	result := ExtractHTML(text)
+	if len(result) != len(expected) {
+		t.Fatalf("ExtractHTML() returned %d links, want %d", len(result), len(expected))
+	}
+	for i, want := range expected {
+		got := result[i]
+		if got.Text != want.text || got.Result.ASCIIHost != want.host || text[got.Start:got.End] != want.span {
+			t.Errorf("ExtractHTML() result[%d] = {%q %q %q}, want {%q %q %q}",
+				i, got.Text, got.Result.ASCIIHost, text[got.Start:got.End], want.text, want.host, want.span)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Command ngramgen builds data/ngrams.txt, the letter trigram model of the DGA scorer.
+//
+// The model counts letter trigrams in English prose, the public domain text of
+// Newton's Opticks that ships with Go in $GOROOT/src/testdata, and in a list of words
+// common in domain names. Words are padded with '^' and '$' so the model knows
+// how labels start and end. Run it from the module root:
+//
+//	go run ./internal/ngramgen
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// domainWords are frequent in domain names but rare in the prose corpus.
+var domainWords = strings.Fields(`
+	account actor ads agency air app apple apps art auto bank best bet bio blog book books
+	box buy cafe camera car card care cars cash center chat cheap city class click clinic
+	cloud club code coffee college comics company computer connect cook corp craft credit
+	crypto daily data dating deal deals delivery dental design dev digital direct doctor
+	docs domain download drive easy edu energy expert express facebook fashion file film
+	finance fitness flash food forum free fun game games garden gift global go golf google
+	green group guide hair health help home host hosting hotel house hub image info insurance
+	invest jobs just kids lab labs land law learn life link live loan local login love mail
+	main map market marketing media meet mobile money movie music my net network news now
+	office one online open page pay pet phone photo pixel play plus point portal post power
+	press print pro project radio real rent repair review safe sale school search secure
+	security server service shop shopping site smart social soft software solutions sport
+	sports star store stream studio style support team tech technology tel the ticket time
+	today tool tools top tour town trade travel tube tv up update video view vision web
+	webmail wiki win world yahoo your zone amazon microsoft twitter instagram linkedin
+	wikipedia youtube reddit netflix github stackoverflow wordpress paypal ebay walmart
+	target bestbuy nytimes weather dropbox spotify adobe zoom slack salesforce oracle
+	cisco intel samsung sony nike adidas booking airbnb uber lyft tesla baidu yandex
+	alibaba tencent taobao naver outlook office live msn bing duckduckgo mozilla firefox
+	chrome android ubuntu debian python golang java javascript kernel linux apache nginx
+	cloudflare akamai fastly digitalocean heroku vercel netlify shopify stripe square
+	coinbase binance medium substack patreon discord telegram whatsapp signal tiktok
+	snapchat pinterest tumblr quora imgur twitch steam epic roblox minecraft nintendo
+	playstation xbox espn cnn bbc guardian reuters bloomberg forbes wired verge techcrunch
+`)
+
+// domainWeight is how many times each domain word counts, so that the short list
+// weighs about as much as a tenth of the prose.
+const domainWeight = 25
+
+func main() {
+	counts := make(map[string]int)
+
+	corpus := filepath.Join(runtime.GOROOT(), "src", "testdata", "Isaac.Newton-Opticks.txt")
+	f, err := os.Open(corpus)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		addWord(counts, scanner.Text(), 1)
+	}
+	f.Close()
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, w := range domainWords {
+		addWord(counts, w, domainWeight)
+	}
+
+	grams := make([]string, 0, len(counts))
+	for g := range counts {
+		grams = append(grams, g)
+	}
+	sort.Strings(grams)
+
+	out, err := os.Create(filepath.Join("data", "ngrams.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, "# Letter trigram counts for DGA scoring, generated by internal/ngramgen. Do not edit.")
+	for _, g := range grams {
+		fmt.Fprintf(w, "%s %d\n", g, counts[g])
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// addWord counts the trigrams of the letter runs of word, padded with '^' and '$'.
+func addWord(counts map[string]int, word string, weight int) {
+	for _, run := range strings.FieldsFunc(strings.ToLower(word), func(r rune) bool {
+		return r < 'a' || r > 'z'
+	}) {
+		padded := "^" + run + "$"
+		for i := 0; i+3 <= len(padded); i++ {
+			counts[padded[i:i+3]] += weight
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package urlverify
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+)
+
+// IPSet is a set of IPv4 and IPv6 address ranges, stored in a binary prefix trie per
+// address family, answering which range an address falls into. IPv4-mapped IPv6
+// addresses such as ::ffff:192.0.2.1 match IPv4 ranges. The zero value is an empty set;
+// lookups may run concurrently once additions are done.
+type IPSet struct {
+	v4, v6 ipNode
+	size   int
+}
+
+type ipNode struct {
+	children [2]*ipNode
+	prefix   netip.Prefix // Valid if the node ends a prefix of the set
+}
+
+// NewIPSet creates an IPSet holding the given CIDR prefixes or single addresses.
+func NewIPSet(prefixes ...string) (*IPSet, error) {
+	s := &IPSet{}
+	for _, p := range prefixes {
+		if err := s.Add(p); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// LoadIPSet loads an IPSet from files with one CIDR prefix or address per line.
+// Blank lines and comments starting with '#' are ignored.
+func LoadIPSet(paths ...string) (*IPSet, error) {
+	s := &IPSet{}
+	if err := loadFiles(paths, s.Load); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load adds the prefixes read from r, see LoadIPSet. Errors are reported with source
+// and the line number.
+func (s *IPSet) Load(r io.Reader, source string) error {
+	return readLines(r, source, s.Add)
+}
+
+// Add adds a CIDR prefix such as "10.0.0.0/8" or "2001:db8::/32", or a single address.
+// Host bits of the prefix are ignored.
+func (s *IPSet) Add(prefix string) error {
+	var (
+		p   netip.Prefix
+		err error
+	)
+	if strings.Contains(prefix, "/") {
+		p, err = netip.ParsePrefix(prefix)
+	} else {
+		var addr netip.Addr
+		addr, err = netip.ParseAddr(prefix)
+		if err == nil {
+			p = netip.PrefixFrom(addr.WithZone(""), addr.BitLen())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("ip set prefix %q: %w", prefix, err)
+	}
+	s.AddPrefix(p)
+	return nil
+}
+
+// AddPrefix adds a prefix to the set. IPv4-mapped IPv6 prefixes are added as IPv4.
+func (s *IPSet) AddPrefix(p netip.Prefix) {
+	p = p.Masked()
+	if addr := p.Addr(); addr.Is4In6() && p.Bits() >= 96 {
+		p = netip.PrefixFrom(addr.Unmap(), p.Bits()-96)
+	}
+
+	node := s.root(p.Addr())
+	bytes := p.Addr().AsSlice()
+	for i := 0; i < p.Bits(); i++ {
+		bit := bytes[i/8] >> (7 - i%8) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &ipNode{}
+		}
+		node = node.children[bit]
+	}
+
+	if !node.prefix.IsValid() {
+		node.prefix = p
+		s.size++
+	}
+}
+
+// Len returns the number of prefixes in the set.
+func (s *IPSet) Len() int {
+	return s.size
+}
+
+// Contains reports whether addr falls into a prefix of the set.
+func (s *IPSet) Contains(addr netip.Addr) bool {
+	_, ok := s.Lookup(addr)
+	return ok
+}
+
+// Lookup returns the longest prefix of the set containing addr.
+func (s *IPSet) Lookup(addr netip.Addr) (netip.Prefix, bool) {
+	if !addr.IsValid() {
+		return netip.Prefix{}, false
+	}
+	addr = addr.WithZone("").Unmap()
+
+	var (
+		node  = s.root(addr)
+		bytes = addr.AsSlice()
+		match = node.prefix
+	)
+	for i := 0; i < addr.BitLen(); i++ {
+		node = node.children[bytes[i/8]>>(7-i%8)&1]
+		if node == nil {
+			break
+		}
+		if node.prefix.IsValid() {
+			match = node.prefix
+		}
+	}
+	return match, match.IsValid()
+}
+
+func (s *IPSet) root(addr netip.Addr) *ipNode {
+	if addr.Is4() {
+		return &s.v4
+	}
+	return &s.v6
+}
+
+// resultAddr returns the IP address of a URLTypeIP result.
+func resultAddr(result ValidationResult) (netip.Addr, bool) {
+	if result.Type != URLTypeIP {
+		return netip.Addr{}, false
+	}
+	addr, err := netip.ParseAddr(result.TLD)
+	return addr, err == nil
+}
+
+// lookupIP looks up the address of an IP result in set and records the prefix it
+// falls into. ok is false for results that aren't IP addresses.
+func lookupIP(set *IPSet, result *ValidationResult) (found, ok bool) {
+	addr, ok := resultAddr(*result)
+	if !ok {
+		return false, false
+	}
+	if p, found := set.Lookup(addr); found {
+		result.IPPrefix = p
+		return true, true
+	}
+	return false, true
+}
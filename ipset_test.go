@@ -0,0 +1,173 @@
+package urlverify
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIPSetLookup(t *testing.T) {
+	set, err := NewIPSet(
+		"10.0.0.0/8",
+		"10.1.0.0/16",
+		"192.0.2.7",
+		"203.0.113.77/24",
+		"2001:db8::/32",
+		"2001:db8:1::/48",
+		"::ffff:198.51.100.0/120",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 7 {
+		t.Errorf("Len() = %d, want 7", set.Len())
+	}
+
+	tests := []struct {
+		addr   string
+		prefix string
+	}{
+		{"10.2.3.4", "10.0.0.0/8"},
+		{"10.1.2.3", "10.1.0.0/16"},
+		{"11.0.0.1", ""},
+		{"192.0.2.7", "192.0.2.7/32"},
+		{"192.0.2.8", ""},
+		{"203.0.113.1", "203.0.113.0/24"},
+		{"198.51.100.9", "198.51.100.0/24"},
+		{"::ffff:10.1.0.1", "10.1.0.0/16"},
+		{"2001:db8::1", "2001:db8::/32"},
+		{"2001:db8:1::1", "2001:db8:1::/48"},
+		{"fe80::1%eth0", ""},
+		{"2001:db9::1", ""},
+	}
+	for _, tt := range tests {
+		p, ok := set.Lookup(netip.MustParseAddr(tt.addr))
+		if ok != (tt.prefix != "") || ok && p.String() != tt.prefix {
+			t.Errorf("Lookup(%s) = %s, %v, want %q", tt.addr, p, ok, tt.prefix)
+		}
+	}
+
+	if set.Contains(netip.Addr{}) {
+		t.Error("Contains(invalid address) = true")
+	}
+}
+
+func TestIPSetDefaultRoute(t *testing.T) {
+	set, _ := NewIPSet("0.0.0.0/0", "fe80::/10")
+	if p, ok := set.Lookup(netip.MustParseAddr("8.8.8.8")); !ok || p.Bits() != 0 {
+		t.Errorf("Lookup(8.8.8.8) = %s, %v, want 0.0.0.0/0", p, ok)
+	}
+	if !set.Contains(netip.MustParseAddr("fe80::1%eth0")) {
+		t.Error("zoned address not contained")
+	}
+	if set.Contains(netip.MustParseAddr("2001:db8::1")) {
+		t.Error("IPv4 default route matched an IPv6 address")
+	}
+}
+
+func TestIPSetLoad(t *testing.T) {
+	var set IPSet
+	input := "# private ranges\n10.0.0.0/8\n\n172.16.0.0/12 # RFC 1918\n"
+	if err := set.Load(strings.NewReader(input), "private.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 2 || !set.Contains(netip.MustParseAddr("172.20.0.1")) {
+		t.Errorf("Len() = %d, want 2 with 172.16.0.0/12", set.Len())
+	}
+
+	err := set.Load(strings.NewReader("10.0.0.0/8\n10.0.0.0/33\n"), "bad.txt")
+	if err == nil || !strings.HasPrefix(err.Error(), "bad.txt:2: ") {
+		t.Errorf("Load error = %v, want bad.txt:2", err)
+	}
+	if _, err := NewIPSet("example.com"); err == nil {
+		t.Error("NewIPSet(example.com) succeeded")
+	}
+}
+
+func TestLoadIPSetFiles(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	os.WriteFile(first, []byte("192.0.2.0/24\n"), 0o644)
+	os.WriteFile(second, []byte("2001:db8::/32\n"), 0o644)
+
+	set, err := LoadIPSet(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 2 || !set.Contains(netip.MustParseAddr("2001:db8::5")) {
+		t.Errorf("LoadIPSet() Len() = %d, want 2", set.Len())
+	}
+
+	if _, err := LoadIPSet(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("LoadIPSet of a missing file succeeded")
+	}
+}
+
+func TestValidatorIPSet(t *testing.T) {
+	set, _ := NewIPSet("192.0.2.0/24", "2001:db8::/32")
+	v := NewValidator(WithIPSet(set))
+
+	tests := []struct {
+		input  string
+		prefix string
+	}{
+		{"http://192.0.2.10:8080/", "192.0.2.0/24"},
+		{"http://[2001:db8::1]/", "2001:db8::/32"},
+		{"http://[fe80::1%25eth0]/", ""},
+		{"198.51.100.1", ""},
+		{"example.com", ""},
+	}
+	for _, tt := range tests {
+		result := v.ValidateDomain(tt.input)
+		if !result.Valid {
+			t.Errorf("ValidateDomain(%q) invalid: %s", tt.input, result.Reason)
+			continue
+		}
+		got := ""
+		if result.IPPrefix.IsValid() {
+			got = result.IPPrefix.String()
+		}
+		if got != tt.prefix {
+			t.Errorf("ValidateDomain(%q).IPPrefix = %q, want %q", tt.input, got, tt.prefix)
+		}
+	}
+}
+
+func TestExtractorIPSets(t *testing.T) {
+	text := "Hosts: http://10.1.2.3/admin 192.0.2.1 example.org http://[2001:db8::1]/ 8.8.8.8"
+
+	allow, _ := NewIPSet("10.0.0.0/8", "2001:db8::/32")
+	matches := NewExtractor(WithIPAllowList(allow)).FindAll(text)
+	var got []string
+	for _, m := range matches {
+		got = append(got, m.Text)
+	}
+	if want := []string{"http://10.1.2.3/admin", "example.org", "http://[2001:db8::1]/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("allow list = %q, want %q", got, want)
+	}
+	if len(matches) > 0 && matches[0].Result.IPPrefix.String() != "10.0.0.0/8" {
+		t.Errorf("IPPrefix = %s, want 10.0.0.0/8", matches[0].Result.IPPrefix)
+	}
+
+	deny, _ := NewIPSet("192.0.2.0/24", "8.8.8.8")
+	got = NewExtractor(WithIPDenyList(deny)).ExtractAll(text)
+	if want := []string{"http://10.1.2.3/admin", "example.org", "http://[2001:db8::1]/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("deny list = %q, want %q", got, want)
+	}
+}
+
+func BenchmarkIPSetLookup(b *testing.B) {
+	set := &IPSet{}
+	for i := 0; i < 100000; i++ {
+		set.AddPrefix(netip.PrefixFrom(netip.AddrFrom4([4]byte{byte(i >> 16), byte(i >> 8), byte(i), 0}), 24))
+	}
+	addr := netip.MustParseAddr("1.134.159.7")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Contains(addr)
+	}
+}
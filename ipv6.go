@@ -0,0 +1,169 @@
+package urlverify
+
+import (
+	"net/netip"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// escapeZone percent-encodes the '%' before the zone of a bracketed IPv6 literal
+// as RFC 6874 requires, so "[fe80::1%eth0]" parses like "[fe80::1%25eth0]".
+func escapeZone(raw string) string {
+	open := strings.IndexByte(raw, '[')
+	if open < 0 {
+		return raw
+	}
+	end := strings.IndexByte(raw[open:], ']')
+	if end < 0 {
+		return raw
+	}
+	end += open
+	pct := strings.IndexByte(raw[open:end], '%')
+	if pct < 0 || strings.HasPrefix(raw[open+pct:], "%25") {
+		return raw
+	}
+	pct += open
+	return raw[:pct] + "%25" + raw[pct+1:]
+}
+
+// bareIPv6 returns raw as a bracketed URL host if it is an IPv6 address on its own.
+func bareIPv6(raw string) (string, bool) {
+	if strings.Count(raw, ":") < 2 {
+		return "", false
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil || !addr.Is6() {
+		return "", false
+	}
+	return "[" + strings.Replace(raw, "%", "%25", 1) + "]", true
+}
+
+// splitZone splits the zone off an IPv6 host as returned by url.URL.Hostname.
+// ok is false if the host has an empty zone.
+func splitZone(host string) (addr, zone string, ok bool) {
+	i := strings.LastIndexByte(host, '%')
+	if i < 0 || !strings.Contains(host, ":") {
+		return host, "", true
+	}
+	if i == len(host)-1 {
+		return "", "", false
+	}
+	return host[:i], host[i+1:], true
+}
+
+// findBareIPv6 finds IPv6 addresses written without brackets in text. To tell them
+// from words such as "std::move" or times such as "12:30:45", a candidate has to be
+// a valid address, contain a decimal digit and stand on its own: it can't touch
+// letters, digits, colons, brackets or a following dot and digit.
+func findBareIPv6(text string, v *Validator) []Match {
+	if strings.Count(text, ":") < 2 {
+		return nil
+	}
+
+	var matches []Match
+	for i := 0; i < len(text); i++ {
+		if text[i] != ':' {
+			continue
+		}
+		if strings.HasPrefix(text[i:], "://") {
+			i += len("://") - 1
+			continue
+		}
+
+		start, end := ipv6Span(text, i)
+		i = end
+		// A zone can't end in a dot, it's the end of the sentence
+		for end > start && text[end-1] == '.' {
+			end--
+		}
+		raw := text[start:end]
+		if strings.Count(raw, ":") < 2 || strings.IndexFunc(raw, unicode.IsDigit) < 0 {
+			continue
+		}
+		if !isIPv6Boundary(text, start, end) {
+			continue
+		}
+		if result := v.ValidateDomain(raw); result.Valid && result.Type == URLTypeIP {
+			matches = append(matches, Match{
+				Text:   raw,
+				Start:  start,
+				End:    end,
+				Result: result,
+			})
+		}
+	}
+
+	return matches
+}
+
+// ipv6Span returns the run of hex digits, colons and dots around text[i] followed
+// by an optional zone.
+func ipv6Span(text string, i int) (start, end int) {
+	start, end = i, i
+	for start > 0 && isIPv6Char(text[start-1]) {
+		start--
+	}
+	for end < len(text) && isIPv6Char(text[end]) {
+		end++
+	}
+	if end < len(text) && text[end] == '%' {
+		zone := end + 1
+		for zone < len(text) && isZoneChar(text[zone]) {
+			zone++
+		}
+		if zone > end+1 {
+			end = zone
+		}
+	}
+	return start, end
+}
+
+func isIPv6Char(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' || c == ':' || c == '.'
+}
+
+// isZoneChar reports whether c is an unreserved character allowed in a zone (RFC 6874).
+func isZoneChar(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || strings.IndexByte("-._~", c) >= 0
+}
+
+// isIPv6Boundary reports whether text[start:end] isn't part of a longer token.
+func isIPv6Boundary(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(":.[_%", r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(":]_%", r) {
+			return false
+		}
+		if r == '.' && end+1 < len(text) && startsLabel(text[end+1:]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeIPv6 adds the bare IPv6 matches to matches. An IPv6 match replaces the matches
+// it contains, such as the IPv4 part of "::ffff:192.0.2.1", and is dropped if it
+// overlaps any other match, as in the path of a URL.
+func mergeIPv6(matches, v6 []Match) []Match {
+	var preferred []Match
+	for _, m6 := range v6 {
+		keep := true
+		for _, m := range matches {
+			if m.Start < m6.End && m6.Start < m.End && (m.Start < m6.Start || m.End > m6.End) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			preferred = append(preferred, m6)
+		}
+	}
+	return mergeMatches(matches, preferred)
+}
@@ -0,0 +1,87 @@
+package urlverify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateDomainIPv6(t *testing.T) {
+	tests := []struct {
+		input string
+		tld   string
+		zone  string
+		port  int
+	}{
+		{"http://[2001:db8::1]/path", "2001:db8::1", "", 0},
+		{"http://[::ffff:192.0.2.1]:8080/", "192.0.2.1", "", 8080},
+		{"http://[64:ff9b::198.51.100.7]", "64:ff9b::c633:6407", "", 0},
+		{"http://[fe80::1%25eth0]/", "fe80::1", "eth0", 0},
+		{"http://[fe80::1%eth0]:443/", "fe80::1", "eth0", 443},
+		{"[fe80::a%25en0]", "fe80::a", "en0", 0},
+		{"2001:db8::1", "2001:db8::1", "", 0},
+		{"fe80::1%eth0", "fe80::1", "eth0", 0},
+		{"::1", "::1", "", 0},
+	}
+
+	for _, tt := range tests {
+		result := ValidateDomain(tt.input)
+		if !result.Valid || result.Type != URLTypeIP {
+			t.Errorf("ValidateDomain(%q) = %v %v (%s), want a valid IP address", tt.input, result.Valid, result.Type, result.Reason)
+			continue
+		}
+		if result.TLD != tt.tld || result.Zone != tt.zone || result.Port != tt.port {
+			t.Errorf("ValidateDomain(%q) = %q zone %q port %d, want %q zone %q port %d", tt.input,
+				result.TLD, result.Zone, result.Port, tt.tld, tt.zone, tt.port)
+		}
+	}
+
+	for _, input := range []string{"http://[fe80::1%]/", "http://[2001:db8:::1]/", "http://[12345::1]/"} {
+		if result := ValidateDomain(input); result.Valid {
+			t.Errorf("ValidateDomain(%q) is valid, want invalid", input)
+		}
+	}
+}
+
+func TestExtractAllIPv6(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"Connect to http://[::ffff:192.0.2.1]/status now", []string{"http://[::ffff:192.0.2.1]/status"}},
+		{"link-local http://[fe80::1%25eth0]:8080/ here", []string{"http://[fe80::1%25eth0]:8080/"}},
+		{"bare [fe80::1%25eth0] literal", []string{"[fe80::1%25eth0]"}},
+		{"Accepted connection from 2001:db8::1 port 22", []string{"2001:db8::1"}},
+		{"client ::ffff:192.0.2.1 disconnected", []string{"::ffff:192.0.2.1"}},
+		{"peer fe80::1%eth0, retrying", []string{"fe80::1%eth0"}},
+		{"Bound to ::1.", []string{"::1"}},
+		{"full 2001:0db8:0000:0000:0000:ff00:0042:8329 form", []string{"2001:0db8:0000:0000:0000:ff00:0042:8329"}},
+		{"(2001:db8::2)", []string{"2001:db8::2"}},
+		{"std::move and Foo::Bar are not addresses", nil},
+		{"at 12:30:45 the MAC 00:1a:2b:3c:4d:5e changed", nil},
+		{"dead::beef has no digits", nil},
+		{"https://example.com/ipv6/2001:db8::1 stays one URL", []string{"https://example.com/ipv6/2001:db8::1"}},
+		{"2001:db8::1:x", nil},
+	}
+
+	for _, tt := range tests {
+		if got := ExtractAll(tt.text); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ExtractAll(%q) = %q, want %q", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestFindAllIPv6Offsets(t *testing.T) {
+	text := "from ::ffff:10.0.0.1 to 10.0.0.2"
+	matches := FindAll(text)
+	if len(matches) != 2 {
+		t.Fatalf("FindAll(%q) found %d matches, want 2", text, len(matches))
+	}
+	for _, m := range matches {
+		if text[m.Start:m.End] != m.Text {
+			t.Errorf("match %q has span %q", m.Text, text[m.Start:m.End])
+		}
+	}
+	if matches[0].Text != "::ffff:10.0.0.1" || matches[1].Text != "10.0.0.2" {
+		t.Errorf("FindAll(%q) = %q, %q", text, matches[0].Text, matches[1].Text)
+	}
+}
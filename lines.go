@@ -0,0 +1,78 @@
+package urlverify
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxLineSize is the longest line the loaders read, generous enough for dumps that
+// put a whole list on one line.
+const maxLineSize = 64 << 20
+
+// scanLines calls fn with every line read from r, trimmed, and its line number.
+// Errors are reported with source and the line number.
+func scanLines(r io.Reader, source string, fn func(text string, line int) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if err := fn(strings.TrimSpace(scanner.Text()), line); err != nil {
+			return fmt.Errorf("%s:%d: %w", source, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	return nil
+}
+
+// readLines calls fn with every line read from r like scanLines, with comments
+// starting with '#' cut off and blank lines skipped.
+func readLines(r io.Reader, source string, fn func(text string) error) error {
+	return scanLines(r, source, func(text string, _ int) error {
+		text, _, _ = strings.Cut(text, "#")
+		if text = strings.TrimSpace(text); text == "" {
+			return nil
+		}
+		return fn(text)
+	})
+}
+
+// loadFiles calls load with each of the files, reporting the path as the source.
+func loadFiles(paths []string, load func(r io.Reader, source string) error) error {
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = load(f, path)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSections reads lines like readLines, passing fn each entry with the name of the
+// "[name]" header it follows. section and entry name the two in errors, such as
+// "category" and "domain".
+func readSections(r io.Reader, source, section, entry string, fn func(name, text string) error) error {
+	var name string
+	return readLines(r, source, func(text string) error {
+		if header, ok := strings.CutPrefix(text, "["); ok {
+			header, ok = strings.CutSuffix(header, "]")
+			if !ok || strings.TrimSpace(header) == "" {
+				return fmt.Errorf("invalid %s header %q", section, text)
+			}
+			name = strings.TrimSpace(header)
+			return nil
+		}
+		if name == "" {
+			return fmt.Errorf("%s %q before the first %s", entry, text, section)
+		}
+		return fn(name, text)
+	})
+}
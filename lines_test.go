@@ -0,0 +1,53 @@
+package urlverify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadLines(t *testing.T) {
+	var got []string
+	text := "# header\n\n  a  \nb # trailing\n#c\n"
+	err := readLines(strings.NewReader(text), "t.txt", func(text string) error {
+		got = append(got, text)
+		return nil
+	})
+	if err != nil || strings.Join(got, ",") != "a,b" {
+		t.Errorf("readLines = %q, %v, want [a b]", got, err)
+	}
+
+	bad := errors.New("bad")
+	err = readLines(strings.NewReader("a\n\nb\n"), "t.txt", func(text string) error {
+		if text == "b" {
+			return bad
+		}
+		return nil
+	})
+	if !errors.Is(err, bad) || err.Error() != "t.txt:3: bad" {
+		t.Errorf("readLines error = %v, want t.txt:3: bad", err)
+	}
+}
+
+func TestReadSections(t *testing.T) {
+	var got []string
+	text := "[ one ]\na\n[two]\nb\nc\n"
+	err := readSections(strings.NewReader(text), "t.txt", "list", "entry", func(name, text string) error {
+		got = append(got, name+"="+text)
+		return nil
+	})
+	if err != nil || strings.Join(got, ",") != "one=a,two=b,two=c" {
+		t.Errorf("readSections = %q, %v", got, err)
+	}
+
+	for text, want := range map[string]string{
+		"a\n[one]\n":  `t.txt:1: entry "a" before the first list`,
+		"[one]\n[]\n": `t.txt:2: invalid list header "[]"`,
+		"[one\n":      `t.txt:1: invalid list header "[one"`,
+	} {
+		err := readSections(strings.NewReader(text), "t.txt", "list", "entry", func(string, string) error { return nil })
+		if err == nil || err.Error() != want {
+			t.Errorf("readSections(%q) error = %v, want %s", text, err, want)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package urlverify
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LinkStatus classifies the outcome of checking whether a URL still works.
+type LinkStatus int
+
+const (
+	LinkOK               LinkStatus = iota // A 2xx response without redirects
+	LinkRedirect                           // A 2xx response after redirects, or a 3xx that can't be followed
+	LinkClientError                        // A 4xx response
+	LinkServerError                        // A 5xx response
+	LinkTimeout                            // The request timed out
+	LinkTLSError                           // The TLS handshake failed, e.g. an invalid certificate
+	LinkDNSError                           // The host name couldn't be resolved
+	LinkTooManyRedirects                   // The redirect limit was reached
+	LinkError                              // Any other failure, e.g. a refused connection
+	LinkInvalid                            // The URL isn't a valid http or https URL
+)
+
+func (s LinkStatus) String() string {
+	switch s {
+	case LinkOK:
+		return "OK"
+	case LinkRedirect:
+		return "Redirect"
+	case LinkClientError:
+		return "Client Error"
+	case LinkServerError:
+		return "Server Error"
+	case LinkTimeout:
+		return "Timeout"
+	case LinkTLSError:
+		return "TLS Error"
+	case LinkDNSError:
+		return "DNS Error"
+	case LinkTooManyRedirects:
+		return "Too Many Redirects"
+	case LinkError:
+		return "Error"
+	case LinkInvalid:
+		return "Invalid URL"
+	default:
+		return "Unknown"
+	}
+}
+
+// Hop is a redirect followed while checking a URL.
+type Hop struct {
+	URL        string // The URL that redirected
+	StatusCode int    // The redirect status, e.g. 301
+}
+
+// LinkResult is the outcome of checking a URL.
+type LinkResult struct {
+	URL        string     // The URL checked
+	Status     LinkStatus // Classification of the outcome
+	StatusCode int        // HTTP status of the last response, 0 if there was none
+	Method     string     // Method of the last request, GET if HEAD wasn't answered properly
+	FinalURL   string     // URL of the last request
+	Redirects  []Hop      // Redirects followed, in order
+	Err        error      // The request error, if there was no response
+}
+
+// Checker checks whether URLs still work with HTTP requests. It tries HEAD first and
+// falls back to GET when the server answers HEAD with an error, follows redirects up
+// to a limit and limits the requests sent to every host. A Checker is safe for
+// concurrent use.
+type Checker struct {
+	client       *http.Client
+	maxRedirects int
+	concurrency  int
+	hostLimit    int
+	hostInterval time.Duration
+	timeout      time.Duration
+	userAgent    string
+
+	mu      sync.Mutex
+	hosts   map[string]*hostLimiter
+	sweepAt int // Number of hosts from which idle limiters are dropped
+}
+
+// hostLimiter limits the requests sent to a host at the same time and how often
+// they start. It is dropped once no request uses it and the interval has passed.
+type hostLimiter struct {
+	sem   chan struct{}
+	users int // Requests holding or waiting for the limiter, guarded by Checker.mu
+
+	mu   sync.Mutex
+	next time.Time // When the next request may start
+}
+
+// minSweep is the number of hosts from which Checker looks for idle limiters.
+const minSweep = 64
+
+// CheckerOption configures a Checker.
+type CheckerOption func(*Checker)
+
+// WithHTTPClient makes the Checker send requests through client, for its transport,
+// proxy or cookie settings. Its redirect policy is replaced, redirects are followed
+// by the Checker. http.DefaultClient is used by default.
+func WithHTTPClient(client *http.Client) CheckerOption {
+	return func(c *Checker) {
+		c.client = client
+	}
+}
+
+// WithMaxRedirects sets how many redirects are followed, 10 by default.
+func WithMaxRedirects(n int) CheckerOption {
+	return func(c *Checker) {
+		c.maxRedirects = n
+	}
+}
+
+// WithConcurrency sets how many URLs CheckAll checks at the same time, 8 by default.
+func WithConcurrency(n int) CheckerOption {
+	return func(c *Checker) {
+		c.concurrency = n
+	}
+}
+
+// WithHostConcurrency sets how many requests are sent to the same host at the same
+// time, 2 by default.
+func WithHostConcurrency(n int) CheckerOption {
+	return func(c *Checker) {
+		c.hostLimit = n
+	}
+}
+
+// WithHostInterval sets the minimum time between the start of two requests to the
+// same host, no limit by default.
+func WithHostInterval(d time.Duration) CheckerOption {
+	return func(c *Checker) {
+		c.hostInterval = d
+	}
+}
+
+// WithCheckTimeout sets how long checking a URL may take in total, including
+// redirects and waiting for the host limits, 30 seconds by default.
+func WithCheckTimeout(d time.Duration) CheckerOption {
+	return func(c *Checker) {
+		c.timeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests.
+func WithUserAgent(userAgent string) CheckerOption {
+	return func(c *Checker) {
+		c.userAgent = userAgent
+	}
+}
+
+// NewChecker creates a Checker with the given options.
+func NewChecker(opts ...CheckerOption) *Checker {
+	c := &Checker{
+		client:       http.DefaultClient,
+		maxRedirects: 10,
+		concurrency:  8,
+		hostLimit:    2,
+		timeout:      30 * time.Second,
+		hosts:        make(map[string]*hostLimiter),
+		sweepAt:      minSweep,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	client := *c.client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	c.client = &client
+	return c
+}
+
+// CheckAll checks the URLs concurrently and returns the results in the same order.
+func (c *Checker) CheckAll(ctx context.Context, urls []string) []LinkResult {
+	results := make([]LinkResult, len(urls))
+	sem := make(chan struct{}, max(c.concurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, raw := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = c.Check(ctx, raw)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Check checks whether a URL still works. URLs without a scheme, as returned by
+// ExtractAll for bare domains, are checked over http.
+func (c *Checker) Check(ctx context.Context, raw string) LinkResult {
+	result := LinkResult{URL: raw}
+	u, err := ParseURL(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		result.Status, result.Err = LinkInvalid, err
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	for {
+		result.FinalURL = u.String()
+		resp, method, err := c.fetch(ctx, u)
+		result.Method = method
+		if err != nil {
+			result.Status, result.Err = classifyLinkError(err), err
+			return result
+		}
+		result.StatusCode = resp.StatusCode
+
+		location := resp.Header.Get("Location")
+		if !isRedirect(resp.StatusCode) || location == "" {
+			result.Status = classifyLinkStatus(resp.StatusCode, len(result.Redirects) > 0)
+			return result
+		}
+
+		next, err := u.Parse(location)
+		if err != nil {
+			result.Status, result.Err = LinkRedirect, fmt.Errorf("invalid redirect location %q: %w", location, err)
+			return result
+		}
+		if len(result.Redirects) >= c.maxRedirects {
+			result.Status = LinkTooManyRedirects
+			return result
+		}
+		result.Redirects = append(result.Redirects, Hop{URL: u.String(), StatusCode: resp.StatusCode})
+		u = next
+	}
+}
+
+// fetch sends a HEAD request for u, falling back to GET if the server answers with
+// an error status, as some don't implement HEAD. The body is discarded.
+func (c *Checker) fetch(ctx context.Context, u *url.URL) (*http.Response, string, error) {
+	resp, err := c.do(ctx, http.MethodHead, u)
+	if err != nil || resp.StatusCode < 400 {
+		return resp, http.MethodHead, err
+	}
+	resp, err = c.do(ctx, http.MethodGet, u)
+	return resp, http.MethodGet, err
+}
+
+func (c *Checker) do(ctx context.Context, method string, u *url.URL) (*http.Response, error) {
+	host := strings.ToLower(u.Host)
+	limiter := c.limiter(host)
+	defer c.done(host, limiter)
+	if err := limiter.acquire(ctx, c.hostInterval); err != nil {
+		return nil, err
+	}
+	defer limiter.release()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// Read a little of the body so the connection can be reused
+	io.CopyN(io.Discard, resp.Body, 64*1024)
+	resp.Body.Close()
+	return resp, nil
+}
+
+// limiter returns the limiter of host, to be handed back with done.
+func (c *Checker) limiter(host string) *hostLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.hosts[host]
+	if !ok {
+		// Limiters that didn't go when their last request ended, as the interval
+		// hadn't passed yet, are dropped once the hosts double
+		if len(c.hosts) >= c.sweepAt {
+			now := time.Now()
+			for h, idle := range c.hosts {
+				if idle.users == 0 && idle.idle(now) {
+					delete(c.hosts, h)
+				}
+			}
+			c.sweepAt = max(2*len(c.hosts), minSweep)
+		}
+		l = &hostLimiter{sem: make(chan struct{}, max(c.hostLimit, 1))}
+		c.hosts[host] = l
+	}
+	l.users++
+	return l
+}
+
+// done hands back a limiter returned by limiter, dropping it if it is idle.
+func (c *Checker) done(host string, l *hostLimiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l.users--
+	if l.users == 0 && l.idle(time.Now()) && c.hosts[host] == l {
+		delete(c.hosts, host)
+	}
+}
+
+// idle reports whether a request may start at once, so that a new limiter would
+// do the same.
+func (l *hostLimiter) idle(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.next.After(now)
+}
+
+// acquire waits for a free request slot and for interval to pass since the previous
+// request started.
+func (l *hostLimiter) acquire(ctx context.Context, interval time.Duration) error {
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(interval)
+	l.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			l.release()
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (l *hostLimiter) release() {
+	<-l.sem
+}
+
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+func classifyLinkStatus(code int, redirected bool) LinkStatus {
+	switch {
+	case code >= 500:
+		return LinkServerError
+	case code >= 400:
+		return LinkClientError
+	case code >= 300 || redirected:
+		return LinkRedirect
+	default:
+		return LinkOK
+	}
+}
+
+// classifyLinkError classifies the error of a request without a response.
+func classifyLinkError(err error) LinkStatus {
+	var (
+		dnsErr       *net.DNSError
+		netErr       net.Error
+		verifyErr    *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &dnsErr) && !dnsErr.IsTimeout:
+		return LinkDNSError
+	case errors.As(err, &verifyErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return LinkTLSError
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return LinkTimeout
+	default:
+		return LinkError
+	}
+}
@@ -0,0 +1,194 @@
+package urlverify
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newLinkServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/temp", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/temp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCheckerCheck(t *testing.T) {
+	server := newLinkServer()
+	defer server.Close()
+	c := NewChecker(WithHTTPClient(server.Client()), WithMaxRedirects(3), WithCheckTimeout(100*time.Millisecond))
+
+	tests := []struct {
+		path      string
+		status    LinkStatus
+		code      int
+		method    string
+		redirects int
+	}{
+		{"/ok", LinkOK, 200, "HEAD", 0},
+		{"/no-head", LinkOK, 200, "GET", 0},
+		{"/moved", LinkRedirect, 200, "HEAD", 2},
+		{"/loop", LinkTooManyRedirects, 302, "HEAD", 3},
+		{"/gone", LinkClientError, 410, "GET", 0},
+		{"/missing", LinkClientError, 404, "GET", 0},
+		{"/broken", LinkServerError, 500, "GET", 0},
+		{"/slow", LinkTimeout, 0, "HEAD", 0},
+	}
+
+	for _, tt := range tests {
+		r := c.Check(context.Background(), server.URL+tt.path)
+		if r.Status != tt.status || r.StatusCode != tt.code || r.Method != tt.method || len(r.Redirects) != tt.redirects {
+			t.Errorf("Check(%s) = %v %d %s %d redirects, want %v %d %s %d (err %v)", tt.path,
+				r.Status, r.StatusCode, r.Method, len(r.Redirects), tt.status, tt.code, tt.method, tt.redirects, r.Err)
+		}
+	}
+
+	r := c.Check(context.Background(), server.URL+"/moved")
+	if r.FinalURL != server.URL+"/ok" || r.Redirects[0].URL != server.URL+"/moved" || r.Redirects[0].StatusCode != 301 ||
+		r.Redirects[1].URL != server.URL+"/temp" || r.Redirects[1].StatusCode != 302 {
+		t.Errorf("redirect chain = %+v, final %s", r.Redirects, r.FinalURL)
+	}
+
+	if r := c.Check(context.Background(), "ftp://example.com/file"); r.Status != LinkInvalid {
+		t.Errorf("Check(ftp) = %v", r.Status)
+	}
+}
+
+func TestCheckerTransportErrors(t *testing.T) {
+	tlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0) // The failed handshake is expected
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	// The default client doesn't trust the test certificate
+	if r := NewChecker().Check(context.Background(), tlsServer.URL); r.Status != LinkTLSError {
+		t.Errorf("untrusted certificate = %v (%v), want TLS error", r.Status, r.Err)
+	}
+	if r := NewChecker(WithHTTPClient(tlsServer.Client())).Check(context.Background(), tlsServer.URL); r.Status != LinkOK {
+		t.Errorf("trusted certificate = %v (%v), want OK", r.Status, r.Err)
+	}
+
+	dnsClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}}
+		},
+	}}
+	if r := NewChecker(WithHTTPClient(dnsClient)).Check(context.Background(), "https://missing.example.com/"); r.Status != LinkDNSError {
+		t.Errorf("unresolvable host = %v (%v), want DNS error", r.Status, r.Err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	addr := server.URL
+	server.Close()
+	if r := NewChecker().Check(context.Background(), addr); r.Status != LinkError {
+		t.Errorf("refused connection = %v (%v), want error", r.Status, r.Err)
+	}
+}
+
+func TestCheckerHostLimits(t *testing.T) {
+	var (
+		mu           sync.Mutex
+		running, top int
+		starts       []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		top = max(top, running)
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	urls := []string{server.URL + "/a", server.URL + "/b", server.URL + "/c", server.URL + "/d"}
+	c := NewChecker(WithHTTPClient(server.Client()), WithHostConcurrency(1), WithHostInterval(20*time.Millisecond))
+	results := c.CheckAll(context.Background(), urls)
+
+	for i, r := range results {
+		if r.URL != urls[i] || r.Status != LinkOK {
+			t.Errorf("CheckAll()[%d] = %s %v", i, r.URL, r.Status)
+		}
+	}
+	if top != 1 {
+		t.Errorf("%d requests ran at once, want 1", top)
+	}
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 15*time.Millisecond {
+			t.Errorf("requests %d and %d started %v apart", i-1, i, gap)
+		}
+	}
+}
+
+func TestCheckerDropsIdleHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	ctx := context.Background()
+
+	c := NewChecker(WithHTTPClient(server.Client()))
+	c.CheckAll(ctx, []string{server.URL + "/a", server.URL + "/b"})
+	if n := len(c.hosts); n != 0 {
+		t.Errorf("%d host limiters left after the checks, want 0", n)
+	}
+
+	// With an interval the limiter stays until it has passed, and goes with the next sweep
+	c = NewChecker(WithHTTPClient(server.Client()), WithHostInterval(10*time.Millisecond))
+	c.CheckAll(ctx, []string{server.URL + "/a"})
+	if n := len(c.hosts); n != 1 {
+		t.Errorf("%d host limiters left right after the check, want 1", n)
+	}
+	time.Sleep(20 * time.Millisecond)
+	c.sweepAt = 1
+	c.done("other.example.com", c.limiter("other.example.com"))
+	if n := len(c.hosts); n != 0 {
+		t.Errorf("%d host limiters left after the sweep, want 0", n)
+	}
+}
+
+func TestCheckerExtractedURLs(t *testing.T) {
+	server := newLinkServer()
+	defer server.Close()
+
+	text := "See " + server.URL + "/ok and " + server.URL + "/gone for details."
+	results := NewChecker(WithHTTPClient(server.Client())).CheckAll(context.Background(), ExtractAll(text))
+	if len(results) != 2 || results[0].Status != LinkOK || results[1].Status != LinkClientError {
+		t.Errorf("CheckAll(ExtractAll()) = %+v", results)
+	}
+	if LinkTLSError.String() != "TLS Error" {
+		t.Errorf("String() = %q", LinkTLSError)
+	}
+}
@@ -0,0 +1,553 @@
+package urlverify
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	mdFenceRegex      = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	mdListItemRegex   = regexp.MustCompile(`^ {0,3}(?:[-*+]|\d{1,9}[.)])(?:[ \t]|$)`)
+	mdDefinitionRegex = regexp.MustCompile(`^ {0,3}\[((?:[^\]\\]|\\.)+)\]:[ \t]*(<[^<>\n]*>|\S+)(?:[ \t]+("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\((?:[^()\\]|\\.)*\)))?[ \t]*$`)
+	mdAutolinkRegex   = regexp.MustCompile(`^<[a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*>`)
+)
+
+// MarkdownLinkKind describes the Markdown construct a link was found in.
+type MarkdownLinkKind int
+
+const (
+	MarkdownInline     MarkdownLinkKind = iota // [text](url)
+	MarkdownReference                          // [text][label], [label][] or [label]
+	MarkdownDefinition                         // [label]: url that is never referenced
+	MarkdownAutolink                           // <url>
+	MarkdownImage                              // ![alt](url) or ![alt][label]
+	MarkdownBare                               // URL or domain in plain text
+)
+
+func (k MarkdownLinkKind) String() string {
+	switch k {
+	case MarkdownInline:
+		return "Inline"
+	case MarkdownReference:
+		return "Reference"
+	case MarkdownDefinition:
+		return "Definition"
+	case MarkdownAutolink:
+		return "Autolink"
+	case MarkdownImage:
+		return "Image"
+	case MarkdownBare:
+		return "Bare"
+	default:
+		return "Unknown"
+	}
+}
+
+// MarkdownLink represents a valid URL found in Markdown text.
+// The embedded Match holds the destination exactly as written and its position in the text;
+// for reference links the position is that of the matching definition.
+type MarkdownLink struct {
+	Match
+	Kind     MarkdownLinkKind // Construct the URL was found in
+	LinkText string           // Link text, image alt text or reference label
+	Title    string           // Link title, if present
+
+	pos int // Start of the construct, used for ordering
+}
+
+// MarkdownOption configures ExtractMarkdown.
+type MarkdownOption func(*markdownConfig)
+
+type markdownConfig struct {
+	skipCode bool
+}
+
+// MarkdownSkipCode makes ExtractMarkdown ignore URLs inside code spans, fenced and indented code blocks.
+func MarkdownSkipCode() MarkdownOption {
+	return func(c *markdownConfig) {
+		c.skipCode = true
+	}
+}
+
+type markdownDefinition struct {
+	label      string
+	dest       string
+	title      string
+	start, end int // Position of the destination
+	pos        int // Start of the definition line
+	used       bool
+}
+
+type markdownParser struct {
+	src   string
+	cfg   markdownConfig
+	mask  []byte // Copy of src with consumed constructs blanked out, scanned for bare URLs
+	defs  map[string]*markdownDefinition
+	order []*markdownDefinition
+	links []MarkdownLink
+}
+
+// ExtractMarkdown extracts all valid URLs from Markdown text together with their link text.
+// It understands inline links, reference links and definitions, autolinks and images;
+// URLs in the remaining text are reported as MarkdownBare. Relative links and non-web
+// schemes such as mailto: are ignored.
+func ExtractMarkdown(text string, opts ...MarkdownOption) []MarkdownLink {
+	p := &markdownParser{
+		src:  text,
+		mask: []byte(text),
+		defs: make(map[string]*markdownDefinition),
+	}
+	for _, opt := range opts {
+		opt(&p.cfg)
+	}
+
+	for _, seg := range p.scanBlocks() {
+		p.scanInline(seg[0], seg[1])
+	}
+
+	for _, def := range p.order {
+		if def.used {
+			continue
+		}
+		p.addLink(def.pos, def.start, def.end, def.dest, MarkdownDefinition, def.label, def.title)
+	}
+
+	for _, m := range FindAll(string(p.mask)) {
+		m.Text = text[m.Start:m.End]
+		p.links = append(p.links, MarkdownLink{Match: m, Kind: MarkdownBare, pos: m.Start})
+	}
+
+	sort.SliceStable(p.links, func(i, j int) bool {
+		return p.links[i].pos < p.links[j].pos
+	})
+
+	return p.links
+}
+
+// scanBlocks walks the text line by line, handling code blocks and reference definitions,
+// and returns the ranges of the remaining text that need inline parsing.
+func (p *markdownParser) scanBlocks() [][2]int {
+	var (
+		segments   [][2]int
+		segStart   = -1
+		fence      string
+		prevBlank  = true
+		prevCode   = false
+		inList     = false
+		flushUntil = func(end int) {
+			if segStart >= 0 {
+				segments = append(segments, [2]int{segStart, end})
+				segStart = -1
+			}
+		}
+	)
+
+	for start := 0; start < len(p.src); {
+		end := strings.IndexByte(p.src[start:], '\n')
+		next := len(p.src)
+		if end < 0 {
+			end = len(p.src)
+		} else {
+			end += start
+			next = end + 1
+		}
+		line := p.src[start:end]
+		blank := strings.TrimSpace(line) == ""
+
+		switch {
+		case fence != "":
+			if isClosingFence(line, fence) {
+				fence = ""
+			}
+			p.codeBlock(start, end)
+			prevCode = false
+
+		case openingFence(line) != "":
+			flushUntil(start)
+			fence = openingFence(line)
+			p.codeBlock(start, end)
+			prevCode = false
+
+		case !blank && (prevBlank || prevCode) && !inList && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")):
+			flushUntil(start)
+			p.codeBlock(start, end)
+			prevCode = true
+
+		case mdDefinitionRegex.MatchString(line):
+			flushUntil(start)
+			p.definition(start, line)
+			prevCode = false
+
+		default:
+			if !blank {
+				prevCode = false
+				if mdListItemRegex.MatchString(line) {
+					inList = true
+				} else if line[0] != ' ' && line[0] != '\t' && prevBlank {
+					inList = false
+				}
+			}
+			if segStart < 0 {
+				segStart = start
+			}
+		}
+
+		prevBlank = blank
+		start = next
+	}
+	flushUntil(len(p.src))
+
+	return segments
+}
+
+// openingFence returns the fence that opens a fenced code block on the line, if any.
+func openingFence(line string) string {
+	m := mdFenceRegex.FindStringSubmatchIndex(line)
+	if m == nil {
+		return ""
+	}
+	fence := line[m[2]:m[3]]
+	if fence[0] == '`' && strings.Contains(line[m[3]:], "`") {
+		// Backtick fences can't have backticks in the info string
+		return ""
+	}
+	return fence
+}
+
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	rest := strings.TrimLeft(trimmed, fence[:1])
+	return len(trimmed)-len(rest) >= len(fence) && strings.TrimSpace(rest) == ""
+}
+
+func (p *markdownParser) codeBlock(start, end int) {
+	if p.cfg.skipCode {
+		p.blank(start, end)
+	}
+}
+
+func (p *markdownParser) definition(start int, line string) {
+	m := mdDefinitionRegex.FindStringSubmatchIndex(line)
+	label := normalizeMarkdownLabel(line[m[2]:m[3]])
+	dest, destStart, destEnd := line[m[4]:m[5]], start+m[4], start+m[5]
+	if strings.HasPrefix(dest, "<") {
+		dest, destStart, destEnd = dest[1:len(dest)-1], destStart+1, destEnd-1
+	}
+	title := ""
+	if m[6] >= 0 {
+		title = unescapeMarkdown(line[m[6]+1 : m[7]-1])
+	}
+
+	p.blank(start, start+len(line))
+	if _, ok := p.defs[label]; ok {
+		// The first definition of a label takes precedence
+		return
+	}
+	def := &markdownDefinition{
+		label: line[m[2]:m[3]],
+		dest:  dest,
+		title: title,
+		start: destStart,
+		end:   destEnd,
+		pos:   start,
+	}
+	p.defs[label] = def
+	p.order = append(p.order, def)
+}
+
+// scanInline finds code spans, autolinks, links and images in src[start:end].
+func (p *markdownParser) scanInline(start, end int) {
+	for i := start; i < end; {
+		switch p.src[i] {
+		case '\\':
+			i += 2
+
+		case '`':
+			n := backtickRun(p.src, i, end)
+			if close := findBacktickRun(p.src, i+n, end, n); close >= 0 {
+				if p.cfg.skipCode {
+					p.blank(i, close+n)
+				} else {
+					// Keep the delimiters from sticking to URLs in the span
+					p.blank(i, i+n)
+					p.blank(close, close+n)
+				}
+				i = close + n
+			} else {
+				i += n
+			}
+
+		case '<':
+			if loc := mdAutolinkRegex.FindStringIndex(p.src[i:end]); loc != nil {
+				p.addLink(i, i+1, i+loc[1]-1, p.src[i+1:i+loc[1]-1], MarkdownAutolink, "", "")
+				p.blank(i, i+loc[1])
+				i += loc[1]
+			} else {
+				i++
+			}
+
+		case '!':
+			if i+1 < end && p.src[i+1] == '[' {
+				if next, ok := p.link(i, i+1, end, true); ok {
+					i = next
+					continue
+				}
+			}
+			i++
+
+		case '[':
+			if next, ok := p.link(i, i, end, false); ok {
+				i = next
+			} else {
+				i++
+			}
+
+		default:
+			i++
+		}
+	}
+}
+
+// link parses a link or image whose label starts at src[open] and returns the position just after it.
+func (p *markdownParser) link(pos, open, end int, image bool) (int, bool) {
+	close := findCloseBracket(p.src, open, end)
+	if close < 0 {
+		return 0, false
+	}
+	label := p.src[open+1 : close]
+	kind := MarkdownInline
+	if image {
+		kind = MarkdownImage
+	}
+
+	next := -1
+	if close+1 < end && p.src[close+1] == '(' {
+		if dest, title, destStart, destEnd, n, ok := parseInlineDestination(p.src, close+1, end); ok {
+			p.addLink(pos, destStart, destEnd, dest, kind, label, title)
+			next = n
+		}
+	}
+
+	if next < 0 {
+		ref, refEnd := label, close+1
+		if close+1 < end && p.src[close+1] == '[' {
+			if refClose := findCloseBracket(p.src, close+1, end); refClose >= 0 {
+				if refClose > close+2 {
+					ref = p.src[close+2 : refClose]
+				}
+				refEnd = refClose + 1
+			}
+		}
+		def, ok := p.defs[normalizeMarkdownLabel(ref)]
+		if !ok {
+			return 0, false
+		}
+		def.used = true
+		if !image {
+			kind = MarkdownReference
+		}
+		p.addLink(pos, def.start, def.end, def.dest, kind, label, def.title)
+		next = refEnd
+	}
+
+	// Link text may contain images, as in badge links
+	p.scanInline(open+1, close)
+	p.blank(pos, next)
+
+	return next, true
+}
+
+func (p *markdownParser) addLink(pos, start, end int, dest string, kind MarkdownLinkKind, label, title string) {
+	dest = unescapeMarkdown(dest)
+	if !isWebDestination(dest) {
+		return
+	}
+
+	result := ValidateDomain(dest)
+	if !result.Valid {
+		return
+	}
+
+	p.links = append(p.links, MarkdownLink{
+		Match: Match{
+			Text:   dest,
+			Start:  start,
+			End:    end,
+			Result: result,
+		},
+		Kind:     kind,
+		LinkText: label,
+		Title:    title,
+		pos:      pos,
+	})
+}
+
+// blank hides src[start:end] from the bare URL scan while keeping byte offsets intact.
+func (p *markdownParser) blank(start, end int) {
+	for i := start; i < end; i++ {
+		if p.mask[i] != '\n' {
+			p.mask[i] = ' '
+		}
+	}
+}
+
+// isWebDestination reports whether a link destination is an absolute or scheme-less web URL,
+// as opposed to a relative path, a fragment or a URL with a non-web scheme such as mailto:.
+func isWebDestination(dest string) bool {
+	if dest == "" || dest[0] == '/' && !strings.HasPrefix(dest, "//") || dest[0] == '#' || dest[0] == '?' || dest[0] == '.' {
+		return false
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme == "" {
+		return true
+	}
+	scheme := strings.ToLower(u.Scheme)
+
+	// "example.com:8080/path" parses with "example.com" as the scheme
+	return scheme == "http" || scheme == "https" || strings.Contains(scheme, ".")
+}
+
+// parseInlineDestination parses "(dest "title")" starting at src[open] == '('.
+func parseInlineDestination(src string, open, end int) (dest, title string, destStart, destEnd, next int, ok bool) {
+	i := skipMarkdownSpace(src, open+1, end)
+	if i >= end {
+		return
+	}
+
+	if src[i] == '<' {
+		close := strings.IndexAny(src[i+1:end], "<>\n")
+		if close < 0 || src[i+1+close] != '>' {
+			return
+		}
+		destStart, destEnd = i+1, i+1+close
+		i = destEnd + 1
+	} else {
+		destStart = i
+		depth := 0
+	loop:
+		for ; i < end; i++ {
+			switch c := src[i]; {
+			case c == '\\' && i+1 < end:
+				i++
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth == 0 {
+					break loop
+				}
+				depth--
+			case c <= ' ':
+				break loop
+			}
+		}
+		destEnd = i
+	}
+	dest = src[destStart:destEnd]
+
+	j := skipMarkdownSpace(src, i, end)
+	if j < end && j > i && (src[j] == '"' || src[j] == '\'' || src[j] == '(') {
+		closer := src[j]
+		if closer == '(' {
+			closer = ')'
+		}
+		k := j + 1
+		for ; k < end && src[k] != closer; k++ {
+			if src[k] == '\\' {
+				k++
+			}
+		}
+		if k >= end {
+			return
+		}
+		title = unescapeMarkdown(src[j+1 : k])
+		j = skipMarkdownSpace(src, k+1, end)
+	}
+
+	if j >= end || src[j] != ')' {
+		return
+	}
+
+	return dest, title, destStart, destEnd, j + 1, true
+}
+
+func skipMarkdownSpace(src string, i, end int) int {
+	for i < end && (src[i] == ' ' || src[i] == '\t' || src[i] == '\n' || src[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// findCloseBracket returns the position of the ']' matching the '[' at src[open], or -1.
+func findCloseBracket(src string, open, end int) int {
+	depth := 0
+	for i := open + 1; i < end; i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '`':
+			n := backtickRun(src, i, end)
+			if close := findBacktickRun(src, i+n, end, n); close >= 0 {
+				i = close + n - 1
+			} else {
+				i += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func backtickRun(src string, i, end int) int {
+	n := 0
+	for i+n < end && src[i+n] == '`' {
+		n++
+	}
+	return n
+}
+
+// findBacktickRun returns the start of the next run of exactly n backticks in src[from:end], or -1.
+func findBacktickRun(src string, from, end, n int) int {
+	for i := from; i < end; {
+		if src[i] != '`' {
+			i++
+			continue
+		}
+		run := backtickRun(src, i, end)
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+func normalizeMarkdownLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func unescapeMarkdown(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package urlverify
+
+import (
+	"testing"
+)
+
+func TestExtractMarkdown(t *testing.T) {
+	text := "# Docs\n" +
+		"\n" +
+		"See [the guide](https://example.com/guide \"Guide\") and ![logo](https://cdn.example.org/logo.png).\n" +
+		"[![build](https://ci.example.com/badge.svg)](https://ci.example.com/job)\n" +
+		"Read [the spec][spec], the [FAQ][] or just [spec].\n" +
+		"Autolink <https://github.com/potakhov/urlverify> and bare foo.dyndns.org here.\n" +
+		"Ignore [relative](/docs/intro), [anchor](#top) and [mail](mailto:someone@example.com).\n" +
+		"Inline code `https://code.example.com` stays.\n" +
+		"\n" +
+		"```sh\n" +
+		"curl https://fenced.example.com\n" +
+		"```\n" +
+		"\n" +
+		"[spec]: <https://spec.example.com/v1> 'The spec'\n" +
+		"[faq]: https://example.com/faq\n" +
+		"[unused]: https://unused.example.com\n"
+
+	type want struct {
+		text     string
+		kind     MarkdownLinkKind
+		linkText string
+		title    string
+	}
+
+	tests := []struct {
+		description string
+		opts        []MarkdownOption
+		expected    []want
+	}{
+		{
+			description: "default",
+			expected: []want{
+				{"https://example.com/guide", MarkdownInline, "the guide", "Guide"},
+				{"https://cdn.example.org/logo.png", MarkdownImage, "logo", ""},
+				{"https://ci.example.com/job", MarkdownInline, "![build](https://ci.example.com/badge.svg)", ""},
+				{"https://ci.example.com/badge.svg", MarkdownImage, "build", ""},
+				{"https://spec.example.com/v1", MarkdownReference, "the spec", "The spec"},
+				{"https://example.com/faq", MarkdownReference, "FAQ", ""},
+				{"https://spec.example.com/v1", MarkdownReference, "spec", "The spec"},
+				{"https://github.com/potakhov/urlverify", MarkdownAutolink, "", ""},
+				{"foo.dyndns.org", MarkdownBare, "", ""},
+				{"https://code.example.com", MarkdownBare, "", ""},
+				{"https://fenced.example.com", MarkdownBare, "", ""},
+				{"https://unused.example.com", MarkdownDefinition, "unused", ""},
+			},
+		},
+		{
+			description: "skip code",
+			opts:        []MarkdownOption{MarkdownSkipCode()},
+			expected: []want{
+				{"https://example.com/guide", MarkdownInline, "the guide", "Guide"},
+				{"https://cdn.example.org/logo.png", MarkdownImage, "logo", ""},
+				{"https://ci.example.com/job", MarkdownInline, "![build](https://ci.example.com/badge.svg)", ""},
+				{"https://ci.example.com/badge.svg", MarkdownImage, "build", ""},
+				{"https://spec.example.com/v1", MarkdownReference, "the spec", "The spec"},
+				{"https://example.com/faq", MarkdownReference, "FAQ", ""},
+				{"https://spec.example.com/v1", MarkdownReference, "spec", "The spec"},
+				{"https://github.com/potakhov/urlverify", MarkdownAutolink, "", ""},
+				{"foo.dyndns.org", MarkdownBare, "", ""},
+				{"https://unused.example.com", MarkdownDefinition, "unused", ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			result := ExtractMarkdown(text, tt.opts...)
+
+			if len(result) != len(tt.expected) {
+				t.Errorf("ExtractMarkdown() returned %d links, want %d", len(result), len(tt.expected))
+				for _, l := range result {
+					t.Logf("Got: %s %q %q", l.Kind, l.Text, l.LinkText)
+				}
+				return
+			}
+
+			for i, w := range tt.expected {
+				got := result[i]
+				if got.Text != w.text || got.Kind != w.kind || got.LinkText != w.linkText || got.Title != w.title {
+					t.Errorf("ExtractMarkdown() result[%d] = {%q %s %q %q}, want {%q %s %q %q}",
+						i, got.Text, got.Kind, got.LinkText, got.Title, w.text, w.kind, w.linkText, w.title)
+				}
+				if text[got.Start:got.End] != got.Text {
+					t.Errorf("ExtractMarkdown() result[%d] position %d:%d = %q, want %q", i, got.Start, got.End, text[got.Start:got.End], got.Text)
+				}
+				if !got.Result.Valid {
+					t.Errorf("ExtractMarkdown() result[%d] is not valid: %s", i, got.Result.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractMarkdownCodeBlocks(t *testing.T) {
+	text := "Intro paragraph.\n" +
+		"\n" +
+		"    indented https://indented.example.com\n" +
+		"\n" +
+		"- list item\n" +
+		"\n" +
+		"    continuation https://continued.example.com\n" +
+		"\n" +
+		"~~~\n" +
+		"https://tilde.example.com\n" +
+		"~~~\n" +
+		"Spans ``with `nested` https://span.example.com`` end.\n"
+
+	result := ExtractMarkdown(text, MarkdownSkipCode())
+
+	expected := []string{"https://continued.example.com"}
+	if len(result) != len(expected) {
+		t.Errorf("ExtractMarkdown() returned %d links, want %d", len(result), len(expected))
+		for _, l := range result {
+			t.Logf("Got: %s %q", l.Kind, l.Text)
+		}
+		return
+	}
+
+	for i, want := range expected {
+		if result[i].Text != want {
+			t.Errorf("ExtractMarkdown() result[%d] = %q, want %q", i, result[i].Text, want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package urlverify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// messageHeaderKind describes how URLs are harvested from a message header.
+type messageHeaderKind int
+
+const (
+	headerURLList   messageHeaderKind = iota // <url>, <url> as in List-Unsubscribe
+	headerAddresses                          // Address list, the domain of each address is reported
+	headerMessageID                          // <id@host> list, the host of each ID is reported
+)
+
+// messageHeaders lists the headers ExtractMessage harvests.
+var messageHeaders = []struct {
+	name string
+	kind messageHeaderKind
+}{
+	{"List-Unsubscribe", headerURLList},
+	{"List-Subscribe", headerURLList},
+	{"List-Help", headerURLList},
+	{"List-Post", headerURLList},
+	{"List-Archive", headerURLList},
+	{"List-Owner", headerURLList},
+	{"Reply-To", headerAddresses},
+	{"Return-Path", headerAddresses},
+	{"Message-ID", headerMessageID},
+	{"In-Reply-To", headerMessageID},
+	{"References", headerMessageID},
+}
+
+// MessageMatch represents a valid URL or domain found in an email message.
+// Start and End are offsets into the decoded part body or header value the match came from.
+type MessageMatch struct {
+	Match
+	Part        string // MIME part number as used by IMAP ("1", "2.1"), empty for top-level headers
+	ContentType string // Media type of the part, empty for headers
+	Header      string // Header name if the match came from a header
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// ExtractMessage parses an RFC 5322 message and extracts valid URLs and domains from its
+// headers and body. Multipart bodies and attached messages are walked recursively,
+// transfer encodings and charsets are decoded, HTML parts go through ExtractHTML and
+// text parts through FindAll. Non-text parts are skipped.
+//
+// Malformed parts don't stop the walk: the matches found so far are returned
+// together with the first error encountered.
+func ExtractMessage(r io.Reader) ([]MessageMatch, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	w := &messageWalker{}
+	w.headers(textproto.MIMEHeader(msg.Header), "")
+	w.part(textproto.MIMEHeader(msg.Header), msg.Body, "1", "")
+
+	return w.matches, w.err
+}
+
+type messageWalker struct {
+	matches []MessageMatch
+	err     error
+}
+
+func (w *messageWalker) fail(part string, err error) {
+	if w.err == nil {
+		w.err = fmt.Errorf("part %s: %w", part, err)
+	}
+}
+
+// part walks a single MIME entity numbered id; sub is the prefix for numbering its children.
+func (w *messageWalker) part(header textproto.MIMEHeader, body io.Reader, id, sub string) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045 defaults to plain text
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		mr := multipart.NewReader(body, params["boundary"])
+		for n := 1; ; n++ {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				w.fail(id, err)
+				return
+			}
+			child := sub + strconv.Itoa(n)
+			w.part(p.Header, p, child, child+".")
+		}
+
+	case mediaType == "message/rfc822" || mediaType == "message/global":
+		data, err := decodeTransfer(header, body)
+		if err != nil {
+			w.fail(id, err)
+			return
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(data))
+		if err != nil {
+			w.fail(id, err)
+			return
+		}
+		w.headers(textproto.MIMEHeader(msg.Header), id)
+		w.part(textproto.MIMEHeader(msg.Header), msg.Body, id+".1", id+".")
+
+	case strings.HasPrefix(mediaType, "text/"):
+		data, err := decodeTransfer(header, body)
+		if err != nil {
+			w.fail(id, err)
+			return
+		}
+		text := decodeCharset(data, params["charset"])
+
+		if mediaType == "text/html" {
+			for _, l := range ExtractHTML(text) {
+				w.matches = append(w.matches, MessageMatch{Match: l.Match, Part: id, ContentType: mediaType})
+			}
+			return
+		}
+		for _, m := range FindAll(text) {
+			w.matches = append(w.matches, MessageMatch{Match: m, Part: id, ContentType: mediaType})
+		}
+	}
+}
+
+// headers harvests URLs and domains from the headers of the entity numbered part.
+func (w *messageWalker) headers(header textproto.MIMEHeader, part string) {
+	addresses := mail.AddressParser{WordDecoder: wordDecoder}
+
+	for _, h := range messageHeaders {
+		for _, value := range header.Values(h.name) {
+			var found []Match
+
+			switch h.kind {
+			case headerURLList:
+				found = bracketedValues(value, func(s string) (string, bool) {
+					return s, isWebDestination(s)
+				})
+
+			case headerAddresses:
+				list, err := addresses.ParseList(value)
+				if err != nil {
+					continue
+				}
+				for _, addr := range list {
+					at := strings.LastIndexByte(addr.Address, '@')
+					if at < 0 {
+						continue
+					}
+					domain := addr.Address[at+1:]
+					if m, ok := headerMatch(value, domain); ok {
+						found = append(found, m)
+					}
+				}
+
+			case headerMessageID:
+				found = bracketedValues(value, func(s string) (string, bool) {
+					at := strings.LastIndexByte(s, '@')
+					if at < 0 {
+						return "", false
+					}
+					return s[at+1:], true
+				})
+			}
+
+			for _, m := range found {
+				w.matches = append(w.matches, MessageMatch{Match: m, Part: part, Header: h.name})
+			}
+		}
+	}
+}
+
+// bracketedValues validates the <...> items of a header value after mapping them with fn.
+func bracketedValues(value string, fn func(string) (string, bool)) []Match {
+	var found []Match
+	for i := 0; i < len(value); {
+		open := strings.IndexByte(value[i:], '<')
+		if open < 0 {
+			break
+		}
+		open += i
+		close := strings.IndexByte(value[open:], '>')
+		if close < 0 {
+			break
+		}
+		close += open
+
+		item := strings.TrimSpace(value[open+1 : close])
+		if s, ok := fn(item); ok {
+			if m, ok := headerMatch(value[open:close], s); ok {
+				m.Start += open
+				m.End += open
+				found = append(found, m)
+			}
+		}
+		i = close + 1
+	}
+	return found
+}
+
+func headerMatch(value, s string) (Match, bool) {
+	result := ValidateDomain(s)
+	if !result.Valid {
+		return Match{}, false
+	}
+
+	m := Match{Text: s, Result: result}
+	if i := strings.Index(value, s); i >= 0 {
+		m.Start, m.End = i, i+len(s)
+	}
+	return m, true
+}
+
+// decodeTransfer reads body and undoes its Content-Transfer-Encoding.
+func decodeTransfer(header textproto.MIMEHeader, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(body))
+
+	case "base64":
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		data = bytes.Join(bytes.Fields(data), nil)
+		// Tolerate missing padding, which is common in the wild
+		return base64.RawStdEncoding.DecodeString(strings.TrimRight(string(data), "="))
+
+	default:
+		return io.ReadAll(body)
+	}
+}
+
+// decodeCharset converts data in the given charset to UTF-8. Data in an unknown or
+// unsupported charset is returned as it is, URLs are mostly ASCII anyway.
+func decodeCharset(data []byte, charset string) string {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if charset == "" || charset == "utf-8" || charset == "us-ascii" {
+		return string(data)
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return string(data)
+	}
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
@@ -0,0 +1,101 @@
+package urlverify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractMessage(t *testing.T) {
+	message := strings.ReplaceAll(`From: Sender <sender@example.com>
+Reply-To: =?UTF-8?B?0KLQtdGB0YI=?= <reply@reply.example.org>
+Message-ID: <1234.5678@mail.example.net>
+List-Unsubscribe: <mailto:unsub@example.com>, <https://lists.example.com/unsub?id=1>
+Subject: Test
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Plain link https://plain.example.com/a-very-long-path-that-gets-wrapped-by-=
+quoted-printable and foo.dyndns.org
+--inner
+Content-Type: text/html; charset=iso-8859-1
+Content-Transfer-Encoding: base64
+
+PHA+Q2xpY2sgPGEgaHJlZj0iaHR0cHM6Ly9odG1sLmV4YW1wbGUuY29tL2NhZukiPmhlcmU8L2E+
+PC9wPg==
+--inner--
+--outer
+Content-Type: message/rfc822
+
+Message-ID: <abc@forwarded.example.com>
+Content-Type: text/plain
+
+Forwarded https://forwarded.example.com/x
+--outer
+Content-Type: application/octet-stream
+Content-Transfer-Encoding: base64
+
+aHR0cHM6Ly9iaW5hcnkuZXhhbXBsZS5jb20=
+--outer--
+`, "\n", "\r\n")
+
+	expected := []struct {
+		text        string
+		part        string
+		contentType string
+		header      string
+	}{
+		{"https://lists.example.com/unsub?id=1", "", "", "List-Unsubscribe"},
+		{"reply.example.org", "", "", "Reply-To"},
+		{"mail.example.net", "", "", "Message-ID"},
+		{"https://plain.example.com/a-very-long-path-that-gets-wrapped-by-quoted-printable", "1.1", "text/plain", ""},
+		{"foo.dyndns.org", "1.1", "text/plain", ""},
+		{"https://html.example.com/café", "1.2", "text/html", ""},
+		{"forwarded.example.com", "2", "", "Message-ID"},
+		{"https://forwarded.example.com/x", "2.1", "text/plain", ""},
+	}
+
+	result, err := ExtractMessage(strings.NewReader(message))
+	if err != nil {
+		t.Fatalf("ExtractMessage() error = %v", err)
+	}
+
+	if len(result) != len(expected) {
+		t.Errorf("ExtractMessage() returned %d matches, want %d", len(result), len(expected))
+		for _, m := range result {
+			t.Logf("Got: %q part=%q type=%q header=%q", m.Text, m.Part, m.ContentType, m.Header)
+		}
+		return
+	}
+
+	for i, want := range expected {
+		got := result[i]
+		if got.Text != want.text || got.Part != want.part || got.ContentType != want.contentType || got.Header != want.header {
+			t.Errorf("ExtractMessage() result[%d] = {%q %q %q %q}, want {%q %q %q %q}",
+				i, got.Text, got.Part, got.ContentType, got.Header, want.text, want.part, want.contentType, want.header)
+		}
+	}
+}
+
+func TestExtractMessageUnknownCharset(t *testing.T) {
+	message := strings.ReplaceAll(`Subject: Test
+MIME-Version: 1.0
+Content-Type: text/plain; charset=x-unknown-charset
+
+Link https://unknown.example.com/page
+`, "\n", "\r\n")
+
+	result, err := ExtractMessage(strings.NewReader(message))
+	if err != nil {
+		t.Fatalf("ExtractMessage() error = %v", err)
+	}
+	if len(result) != 1 || result[0].Text != "https://unknown.example.com/page" || result[0].Part != "1" {
+		t.Errorf("ExtractMessage() = %+v, want https://unknown.example.com/page in part 1", result)
+	}
+}
@@ -0,0 +1,487 @@
+package urlverify
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNXDomain is returned, wrapped, by Resolvers for names that don't exist.
+var ErrNXDomain = errors.New("no such host")
+
+// Resolver looks up DNS records for ResolveCheck. NewNetResolver adapts a net.Resolver,
+// FakeResolver answers from memory for tests. Names that don't exist are reported
+// with an error wrapping ErrNXDomain.
+type Resolver interface {
+	// LookupHost returns the IPv4 and IPv6 addresses of host, following CNAMEs.
+	LookupHost(ctx context.Context, host string) ([]netip.Addr, error)
+	// LookupCNAME returns the canonical name of host, host itself if it isn't an alias.
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+type netResolver struct {
+	r *net.Resolver
+}
+
+// NewNetResolver returns a Resolver querying DNS through r, or net.DefaultResolver if
+// r is nil. net.Resolver reports names without records of the type looked up as not
+// found, so they are reported as ResolveNXDomain rather than ResolveNoData.
+func NewNetResolver(r *net.Resolver) Resolver {
+	return NewNetMailResolver(r)
+}
+
+// NewNetMailResolver works like NewNetResolver but returns a MailResolver, for MailCheck.
+func NewNetMailResolver(r *net.Resolver) MailResolver {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	return &netResolver{r: r}
+}
+
+func (n *netResolver) LookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	addrs, err := n.r.LookupNetIP(ctx, "ip", host)
+	return addrs, netError(host, err)
+}
+
+func (n *netResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	cname, err := n.r.LookupCNAME(ctx, host)
+	return cname, netError(host, err)
+}
+
+func (n *netResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	mx, err := n.r.LookupMX(ctx, domain)
+	return mx, netError(domain, err)
+}
+
+func (n *netResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	txt, err := n.r.LookupTXT(ctx, name)
+	return txt, netError(name, err)
+}
+
+// netError wraps ErrNXDomain into the not found errors of net.Resolver.
+func netError(host string, err error) error {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return fmt.Errorf("lookup %s: %w", host, ErrNXDomain)
+	}
+	return err
+}
+
+// FakeResolver is a MailResolver answering from records added to it, for running
+// ResolveCheck and MailCheck offline. Names without records don't exist. Add records
+// before using it; lookups are safe for concurrent use.
+type FakeResolver struct {
+	Delay time.Duration // Time every lookup takes, or until the context is done
+
+	addrs   map[string][]netip.Addr
+	cnames  map[string]string
+	mx      map[string][]*net.MX
+	txt     map[string][]string
+	errs    map[string]error
+	lookups atomic.Int64
+}
+
+// NewFakeResolver creates an empty FakeResolver.
+func NewFakeResolver() *FakeResolver {
+	return &FakeResolver{
+		addrs:  make(map[string][]netip.Addr),
+		cnames: make(map[string]string),
+		mx:     make(map[string][]*net.MX),
+		txt:    make(map[string][]string),
+		errs:   make(map[string]error),
+	}
+}
+
+// AddHost adds address records for host. A host added without addresses exists
+// but has no A or AAAA records.
+func (f *FakeResolver) AddHost(host string, addrs ...netip.Addr) {
+	host = canonicalName(host)
+	f.addrs[host] = append(f.addrs[host], addrs...)
+}
+
+// AddCNAME makes alias a CNAME for target.
+func (f *FakeResolver) AddCNAME(alias, target string) {
+	f.cnames[canonicalName(alias)] = canonicalName(target)
+}
+
+// AddMX adds an MX record for domain. A host of "." makes it a null MX (RFC 7505).
+func (f *FakeResolver) AddMX(domain, host string, pref uint16) {
+	domain = canonicalName(domain)
+	f.mx[domain] = append(f.mx[domain], &net.MX{Host: host, Pref: pref})
+}
+
+// AddTXT adds a TXT record for name.
+func (f *FakeResolver) AddTXT(name, txt string) {
+	name = canonicalName(name)
+	f.txt[name] = append(f.txt[name], txt)
+}
+
+// SetError makes lookups of host fail with err.
+func (f *FakeResolver) SetError(host string, err error) {
+	f.errs[canonicalName(host)] = err
+}
+
+// Lookups returns the number of lookups made.
+func (f *FakeResolver) Lookups() int {
+	return int(f.lookups.Load())
+}
+
+func (f *FakeResolver) LookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	name, err := f.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return f.addrs[name], nil
+}
+
+func (f *FakeResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	name, err := f.lookup(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	return name + ".", nil
+}
+
+func (f *FakeResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	name, err := f.lookup(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	var mx []*net.MX
+	for _, r := range f.mx[name] {
+		copied := *r
+		mx = append(mx, &copied)
+	}
+	return mx, nil
+}
+
+func (f *FakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	name, err := f.lookup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return f.txt[name], nil
+}
+
+// lookup follows the CNAMEs of host and returns the canonical name.
+func (f *FakeResolver) lookup(ctx context.Context, host string) (string, error) {
+	f.lookups.Add(1)
+	if f.Delay > 0 {
+		timer := time.NewTimer(f.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	name := canonicalName(host)
+	for range maxCNAMEChain {
+		if err, ok := f.errs[name]; ok {
+			return "", err
+		}
+		target, ok := f.cnames[name]
+		if !ok {
+			break
+		}
+		name = target
+	}
+	if !f.exists(name) {
+		return "", fmt.Errorf("lookup %s: %w", host, ErrNXDomain)
+	}
+	return name, nil
+}
+
+// exists reports whether name has records of any type.
+func (f *FakeResolver) exists(name string) bool {
+	_, addrs := f.addrs[name]
+	_, mx := f.mx[name]
+	_, txt := f.txt[name]
+	return addrs || mx || txt
+}
+
+// maxCNAMEChain limits the CNAMEs FakeResolver follows, as resolvers do.
+const maxCNAMEChain = 16
+
+func canonicalName(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// ResolveStatus is the outcome of resolving a host.
+type ResolveStatus int
+
+const (
+	ResolveOK       ResolveStatus = iota // The host has A or AAAA records
+	ResolveNoData                        // The host exists but has no A or AAAA records
+	ResolveNXDomain                      // The host doesn't exist
+	ResolveTimeout                       // The lookup timed out
+	ResolveError                         // The lookup failed, e.g. SERVFAIL
+)
+
+func (s ResolveStatus) String() string {
+	switch s {
+	case ResolveOK:
+		return "OK"
+	case ResolveNoData:
+		return "No Data"
+	case ResolveNXDomain:
+		return "NXDOMAIN"
+	case ResolveTimeout:
+		return "Timeout"
+	case ResolveError:
+		return "Error"
+	default:
+		return "Unknown"
+	}
+}
+
+// Resolution is the result of resolving a host.
+type Resolution struct {
+	Host   string        // The ASCII host name looked up
+	Status ResolveStatus // Outcome of the lookup
+	IPv4   []netip.Addr  // A records
+	IPv6   []netip.Addr  // AAAA records
+	CNAME  string        // Canonical name if the host is an alias, without the trailing dot
+	Err    error         // The lookup error for ResolveTimeout and ResolveError
+}
+
+// ResolveCheck resolves the hosts of matches through a Resolver. Lookups are cached,
+// run concurrently up to a limit and time out on their own. A ResolveCheck is safe
+// for concurrent use.
+type ResolveCheck struct {
+	resolver    Resolver
+	timeout     time.Duration
+	cacheTTL    time.Duration
+	cacheSize   int
+	concurrency int
+
+	sem    chan struct{}
+	mu     sync.Mutex
+	cache  map[string]*list.Element // Values are *resolveEntry
+	recent *list.List               // Cache entries, most recently used first
+	now    func() time.Time
+}
+
+// resolveEntry is a cached resolution, ready is closed once it is known.
+type resolveEntry struct {
+	host       string
+	ready      chan struct{}
+	resolution Resolution
+	expires    time.Time
+}
+
+// ResolveOption configures a ResolveCheck.
+type ResolveOption func(*ResolveCheck)
+
+// WithResolveTimeout sets how long a single host may take to resolve, 5 seconds by default.
+func WithResolveTimeout(d time.Duration) ResolveOption {
+	return func(c *ResolveCheck) {
+		c.timeout = d
+	}
+}
+
+// WithResolveConcurrency sets how many hosts are resolved at the same time, 8 by default.
+func WithResolveConcurrency(n int) ResolveOption {
+	return func(c *ResolveCheck) {
+		c.concurrency = n
+	}
+}
+
+// WithResolveCacheTTL sets how long resolutions are cached, 5 minutes by default.
+// Timeouts and errors aren't cached; zero disables the cache.
+func WithResolveCacheTTL(d time.Duration) ResolveOption {
+	return func(c *ResolveCheck) {
+		c.cacheTTL = d
+	}
+}
+
+// WithResolveCacheSize sets how many hosts the cache holds, 10000 by default. The
+// least recently used hosts are dropped first.
+func WithResolveCacheSize(n int) ResolveOption {
+	return func(c *ResolveCheck) {
+		c.cacheSize = n
+	}
+}
+
+// NewResolveCheck creates a ResolveCheck resolving through r.
+func NewResolveCheck(r Resolver, opts ...ResolveOption) *ResolveCheck {
+	c := &ResolveCheck{
+		resolver:    r,
+		timeout:     5 * time.Second,
+		cacheTTL:    5 * time.Minute,
+		cacheSize:   10000,
+		concurrency: 8,
+		cache:       make(map[string]*list.Element),
+		recent:      list.New(),
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.sem = make(chan struct{}, max(c.concurrency, 1))
+	return c
+}
+
+// Check resolves the host of every match concurrently and stores the result in
+// Match.Resolution. Each host is looked up once; IP addresses aren't looked up.
+func (c *ResolveCheck) Check(ctx context.Context, matches []Match) {
+	hosts := make(map[string][]int)
+	for i := range matches {
+		if matches[i].Result.Type == URLTypeIP || matches[i].Result.ASCIIHost == "" {
+			continue
+		}
+		host := matches[i].Result.ASCIIHost
+		hosts[host] = append(hosts[host], i)
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for host, indexes := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := c.Resolve(ctx, host)
+			mu.Lock()
+			for _, i := range indexes {
+				matches[i].Resolution = &r
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+// Resolve resolves a single host, using the cache.
+func (c *ResolveCheck) Resolve(ctx context.Context, host string) Resolution {
+	host = canonicalName(host)
+
+	c.mu.Lock()
+	var entry *resolveEntry
+	elem, ok := c.cache[host]
+	if ok {
+		entry = elem.Value.(*resolveEntry)
+		if c.expired(entry) {
+			c.remove(elem)
+			ok = false
+		} else {
+			c.recent.MoveToFront(elem)
+		}
+	}
+	if !ok {
+		entry = &resolveEntry{host: host, ready: make(chan struct{})}
+		c.insert(entry)
+	}
+	c.mu.Unlock()
+
+	if ok {
+		// Another lookup of the host is under way or done
+		select {
+		case <-entry.ready:
+			return entry.resolution
+		case <-ctx.Done():
+			return Resolution{Host: host, Status: ResolveTimeout, Err: ctx.Err()}
+		}
+	}
+
+	r := c.resolve(ctx, host)
+
+	c.mu.Lock()
+	entry.resolution = r
+	entry.expires = c.now().Add(c.cacheTTL)
+	if c.cacheTTL <= 0 || r.Status == ResolveTimeout || r.Status == ResolveError {
+		if elem, ok := c.cache[host]; ok && elem.Value == entry {
+			c.remove(elem)
+		}
+	}
+	c.mu.Unlock()
+	close(entry.ready)
+	return r
+}
+
+// expired reports whether a resolved entry has outlived the cache TTL.
+func (c *ResolveCheck) expired(entry *resolveEntry) bool {
+	return !entry.expires.IsZero() && c.now().After(entry.expires)
+}
+
+// insert adds entry to the cache, first dropping the expired entries at the back
+// and then the least recently used ones while the cache is full. Lookups waiting on
+// a dropped entry still get its resolution.
+func (c *ResolveCheck) insert(entry *resolveEntry) {
+	for back := c.recent.Back(); back != nil && c.expired(back.Value.(*resolveEntry)); back = c.recent.Back() {
+		c.remove(back)
+	}
+	for c.recent.Len() >= max(c.cacheSize, 1) {
+		c.remove(c.recent.Back())
+	}
+	c.cache[entry.host] = c.recent.PushFront(entry)
+}
+
+func (c *ResolveCheck) remove(elem *list.Element) {
+	delete(c.cache, elem.Value.(*resolveEntry).host)
+	c.recent.Remove(elem)
+}
+
+func (c *ResolveCheck) resolve(ctx context.Context, host string) Resolution {
+	r := Resolution{Host: host}
+
+	select {
+	case c.sem <- struct{}{}:
+		defer func() { <-c.sem }()
+	case <-ctx.Done():
+		r.Status, r.Err = ResolveTimeout, ctx.Err()
+		return r
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		r.Status, r.Err = resolveStatus(err), err
+		if r.Status == ResolveNXDomain {
+			r.Err = nil
+		}
+		return r
+	}
+	for _, addr := range addrs {
+		if addr = addr.Unmap(); addr.Is4() {
+			r.IPv4 = append(r.IPv4, addr)
+		} else {
+			r.IPv6 = append(r.IPv6, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		r.Status = ResolveNoData
+	}
+
+	// The canonical name is informational, a failure doesn't change the status
+	if cname, err := c.resolver.LookupCNAME(ctx, host); err == nil {
+		if cname = canonicalName(cname); cname != host {
+			r.CNAME = cname
+		}
+	}
+	return r
+}
+
+// resolveStatus classifies a lookup error.
+func resolveStatus(err error) ResolveStatus {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrNXDomain):
+		return ResolveNXDomain
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ResolveTimeout
+	default:
+		return ResolveError
+	}
+}
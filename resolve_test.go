@@ -0,0 +1,203 @@
+package urlverify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestResolver() *FakeResolver {
+	r := NewFakeResolver()
+	r.AddHost("example.com", netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1"))
+	r.AddHost("cdn.example.net", netip.MustParseAddr("198.51.100.7"))
+	r.AddCNAME("www.example.org", "cdn.example.net")
+	r.AddHost("mail-only.example.com")
+	r.SetError("broken.example.com", errors.New("server misbehaving"))
+	return r
+}
+
+func TestResolveCheckResolve(t *testing.T) {
+	c := NewResolveCheck(newTestResolver())
+	ctx := context.Background()
+
+	tests := []struct {
+		host   string
+		status ResolveStatus
+		ipv4   int
+		ipv6   int
+		cname  string
+	}{
+		{"example.com", ResolveOK, 1, 1, ""},
+		{"EXAMPLE.COM.", ResolveOK, 1, 1, ""},
+		{"www.example.org", ResolveOK, 1, 0, "cdn.example.net"},
+		{"mail-only.example.com", ResolveNoData, 0, 0, ""},
+		{"missing.example.com", ResolveNXDomain, 0, 0, ""},
+		{"broken.example.com", ResolveError, 0, 0, ""},
+	}
+	for _, tt := range tests {
+		r := c.Resolve(ctx, tt.host)
+		if r.Status != tt.status || len(r.IPv4) != tt.ipv4 || len(r.IPv6) != tt.ipv6 || r.CNAME != tt.cname {
+			t.Errorf("Resolve(%q) = %v %v %v %q, want %v", tt.host, r.Status, r.IPv4, r.IPv6, r.CNAME, tt.status)
+		}
+		if (r.Err != nil) != (tt.status == ResolveError) {
+			t.Errorf("Resolve(%q).Err = %v", tt.host, r.Err)
+		}
+	}
+}
+
+func TestResolveCheckCache(t *testing.T) {
+	resolver := newTestResolver()
+	c := NewResolveCheck(resolver)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	c.Resolve(ctx, "example.com")
+	c.Resolve(ctx, "example.com")
+	c.Resolve(ctx, "missing.example.com")
+	c.Resolve(ctx, "missing.example.com")
+	// One LookupHost and one LookupCNAME for example.com, one failed LookupHost for the other
+	if n := resolver.Lookups(); n != 3 {
+		t.Errorf("Lookups() = %d, want 3 with cached results", n)
+	}
+
+	c.Resolve(ctx, "broken.example.com")
+	c.Resolve(ctx, "broken.example.com")
+	if n := resolver.Lookups(); n != 5 {
+		t.Errorf("Lookups() = %d, want 5 as errors aren't cached", n)
+	}
+
+	now = now.Add(10 * time.Minute)
+	c.Resolve(ctx, "example.com")
+	if n := resolver.Lookups(); n != 7 {
+		t.Errorf("Lookups() = %d, want 7 after the cache expired", n)
+	}
+}
+
+func TestResolveCheckCacheEviction(t *testing.T) {
+	resolver := newTestResolver()
+	c := NewResolveCheck(resolver, WithResolveCacheSize(2))
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	c.Resolve(ctx, "example.com")
+	c.Resolve(ctx, "missing.example.com")
+	c.Resolve(ctx, "example.com") // Now the most recently used
+	c.Resolve(ctx, "cdn.example.net")
+	if len(c.cache) != 2 || c.cache["missing.example.com"] != nil {
+		t.Errorf("cache holds %d hosts, want example.com and cdn.example.net", len(c.cache))
+	}
+	before := resolver.Lookups()
+	c.Resolve(ctx, "cdn.example.net")
+	if n := resolver.Lookups(); n != before {
+		t.Errorf("Lookups() = %d, want %d with cdn.example.net cached", n, before)
+	}
+
+	// Expired entries are dropped when the next host is added
+	c = NewResolveCheck(resolver)
+	c.now = func() time.Time { return now }
+	c.Resolve(ctx, "example.com")
+	c.Resolve(ctx, "missing.example.com")
+	now = now.Add(10 * time.Minute)
+	c.Resolve(ctx, "cdn.example.net")
+	if len(c.cache) != 1 || c.recent.Len() != 1 {
+		t.Errorf("cache holds %d hosts after expiry, want 1", len(c.cache))
+	}
+}
+
+func TestResolveCheckTimeout(t *testing.T) {
+	resolver := newTestResolver()
+	resolver.Delay = time.Second
+	c := NewResolveCheck(resolver, WithResolveTimeout(10*time.Millisecond))
+
+	start := time.Now()
+	r := c.Resolve(context.Background(), "example.com")
+	if r.Status != ResolveTimeout || !errors.Is(r.Err, context.DeadlineExceeded) {
+		t.Errorf("Resolve() = %v %v, want timeout", r.Status, r.Err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Resolve() took %v", elapsed)
+	}
+}
+
+// countingResolver records the highest number of lookups running at once.
+type countingResolver struct {
+	Resolver
+	mu           sync.Mutex
+	running, max int
+}
+
+func (c *countingResolver) LookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	c.mu.Lock()
+	c.running++
+	c.max = max(c.max, c.running)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.running--
+		c.mu.Unlock()
+	}()
+	return c.Resolver.LookupHost(ctx, host)
+}
+
+func TestResolveCheckConcurrency(t *testing.T) {
+	fake := NewFakeResolver()
+	fake.Delay = 5 * time.Millisecond
+	var matches []Match
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com", "a.example.com"} {
+		fake.AddHost(host, netip.MustParseAddr("192.0.2.1"))
+		matches = append(matches, Match{Result: ValidateDomain(host)})
+	}
+	counting := &countingResolver{Resolver: fake}
+
+	NewResolveCheck(counting, WithResolveConcurrency(2)).Check(context.Background(), matches)
+	if counting.max > 2 || counting.max == 0 {
+		t.Errorf("%d lookups ran at once, want at most 2", counting.max)
+	}
+	for _, m := range matches {
+		if m.Resolution == nil || m.Resolution.Status != ResolveOK {
+			t.Errorf("%s resolution = %+v", m.Result.ASCIIHost, m.Resolution)
+		}
+	}
+	if matches[0].Resolution != matches[5].Resolution {
+		t.Error("duplicate hosts were resolved separately")
+	}
+}
+
+func TestExtractorResolveCheck(t *testing.T) {
+	c := NewResolveCheck(newTestResolver())
+	text := "Visit https://www.example.org/, https://missing.example.com/ or http://192.0.2.1/"
+
+	matches := NewExtractor(WithResolveCheck(c)).FindAll(text)
+	if len(matches) != 3 {
+		t.Fatalf("FindAll() returned %d matches, want 3", len(matches))
+	}
+	if r := matches[0].Resolution; r == nil || r.Status != ResolveOK || r.CNAME != "cdn.example.net" {
+		t.Errorf("www.example.org resolution = %+v", r)
+	}
+	if r := matches[1].Resolution; r == nil || r.Status != ResolveNXDomain {
+		t.Errorf("missing.example.com resolution = %+v", r)
+	}
+	if matches[2].Resolution != nil {
+		t.Errorf("IP address was resolved: %+v", matches[2].Resolution)
+	}
+}
+
+func TestNetResolverErrors(t *testing.T) {
+	err := netError("missing.example", &net.DNSError{Err: "no such host", Name: "missing.example", IsNotFound: true})
+	if resolveStatus(err) != ResolveNXDomain {
+		t.Errorf("not found error = %v", resolveStatus(err))
+	}
+	err = netError("slow.example", &net.DNSError{Err: "i/o timeout", Name: "slow.example", IsTimeout: true})
+	if resolveStatus(err) != ResolveTimeout {
+		t.Errorf("timeout error = %v", resolveStatus(err))
+	}
+	if s := ResolveNXDomain.String(); s != "NXDOMAIN" {
+		t.Errorf("String() = %q", s)
+	}
+}
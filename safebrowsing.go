@@ -0,0 +1,457 @@
+package urlverify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// HashMatch is a URL expression whose SHA-256 hash starts with a prefix in a
+// HashPrefixDB.
+type HashMatch struct {
+	List       string // Name of the list holding the prefix, e.g. "MALWARE"
+	Expression string // The matching host-suffix/path-prefix expression, e.g. "evil.example.com/"
+	Prefix     []byte // The matching hash prefix, 4 to 32 bytes
+
+	// FullHash reports whether the whole 32-byte hash matched. Shorter prefixes can
+	// collide and are meant to be confirmed against the full hashes of the list.
+	FullHash bool
+}
+
+// HashPrefixDB is a local database of SHA-256 hash prefixes of URL expressions in
+// named lists, the format of the Safe Browsing Update API (v4). URLs are looked up
+// by the hashes of their expressions, see URLExpressions. The zero value is empty;
+// lookups may run concurrently once loading is done.
+type HashPrefixDB struct {
+	// prefixes are indexed by their first 4 bytes, the shortest prefix allowed
+	prefixes map[uint32][]hashPrefix
+	count    int
+}
+
+type hashPrefix struct {
+	list   string
+	prefix []byte
+}
+
+// LoadHashPrefixDB creates a HashPrefixDB with the prefixes loaded from the given
+// files, see HashPrefixDB.Load.
+func LoadHashPrefixDB(paths ...string) (*HashPrefixDB, error) {
+	db := &HashPrefixDB{}
+	if err := loadFiles(paths, db.Load); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Load adds the hash prefixes read from r: a "[list]" header followed by the
+// prefixes in the list, one per line, either in hex or as "size:base64", the
+// prefix size in bytes and the concatenated prefixes of a ThreatEntrySet's rawHashes:
+//
+//	[MALWARE]
+//	4:AAAAAQAAAAIAAAAD
+//	e3b0c44298fc1c149afbf4c8996fb924
+//
+// Blank lines and comments starting with '#' are ignored. Errors are reported with
+// source and the line number.
+func (db *HashPrefixDB) Load(r io.Reader, source string) error {
+	return readSections(r, source, "list", "hash prefix", func(list, text string) error {
+		if size, raw, ok := strings.Cut(text, ":"); ok {
+			return db.addEncodedRawHashes(list, size, raw)
+		}
+		prefix, err := hex.DecodeString(text)
+		if err != nil {
+			return err
+		}
+		return db.Add(list, prefix)
+	})
+}
+
+func (db *HashPrefixDB) addEncodedRawHashes(list, size, raw string) error {
+	n, err := strconv.Atoi(size)
+	if err != nil {
+		return fmt.Errorf("invalid prefix size %q", size)
+	}
+	hashes, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return fmt.Errorf("invalid raw hashes: %w", err)
+	}
+	return db.AddRawHashes(list, n, hashes)
+}
+
+// Add adds a hash prefix of 4 to 32 bytes to a list.
+func (db *HashPrefixDB) Add(list string, prefix []byte) error {
+	if len(prefix) < 4 || len(prefix) > sha256.Size {
+		return fmt.Errorf("hash prefix of %d bytes, want 4 to %d", len(prefix), sha256.Size)
+	}
+	if db.prefixes == nil {
+		db.prefixes = make(map[uint32][]hashPrefix)
+	}
+	key := binary.BigEndian.Uint32(prefix)
+	for _, p := range db.prefixes[key] {
+		if p.list == list && bytes.Equal(p.prefix, prefix) {
+			return nil
+		}
+	}
+	db.prefixes[key] = append(db.prefixes[key], hashPrefix{list: list, prefix: bytes.Clone(prefix)})
+	db.count++
+	return nil
+}
+
+// AddRawHashes adds the prefixes of a Safe Browsing RawHashes entry to a list: raw
+// holds prefixes of prefixSize bytes each, concatenated.
+func (db *HashPrefixDB) AddRawHashes(list string, prefixSize int, raw []byte) error {
+	if prefixSize < 4 || prefixSize > sha256.Size {
+		return fmt.Errorf("prefix size %d, want 4 to %d", prefixSize, sha256.Size)
+	}
+	if len(raw)%prefixSize != 0 {
+		return fmt.Errorf("raw hashes of %d bytes aren't a multiple of the prefix size %d", len(raw), prefixSize)
+	}
+	for i := 0; i < len(raw); i += prefixSize {
+		if err := db.Add(list, raw[i:i+prefixSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddExpression adds the full hash of a URL expression, such as "evil.example.com/"
+// or "example.com/phish/", to a list.
+func (db *HashPrefixDB) AddExpression(list, expression string) {
+	hash := sha256.Sum256([]byte(expression))
+	db.Add(list, hash[:])
+}
+
+// Len returns the number of prefixes in the database, counted once per list.
+func (db *HashPrefixDB) Len() int {
+	return db.count
+}
+
+// Lookup returns the lists holding a prefix of the hash of any expression of the
+// canonical form of a URL or domain, nil if there are none or raw can't be
+// canonicalized.
+func (db *HashPrefixDB) Lookup(raw string) []HashMatch {
+	if db.count == 0 {
+		return nil
+	}
+	expressions, err := URLExpressions(raw)
+	if err != nil {
+		return nil
+	}
+	var matches []HashMatch
+	for _, expression := range expressions {
+		hash := sha256.Sum256([]byte(expression))
+		for _, p := range db.prefixes[binary.BigEndian.Uint32(hash[:])] {
+			if bytes.HasPrefix(hash[:], p.prefix) {
+				matches = append(matches, HashMatch{
+					List:       p.list,
+					Expression: expression,
+					Prefix:     bytes.Clone(p.prefix),
+					FullHash:   len(p.prefix) == sha256.Size,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// WithHashPrefixDB makes the Extractor look up every match it returns in db,
+// reporting the lists it is found in in Match.HashMatches.
+func WithHashPrefixDB(db *HashPrefixDB) ExtractorOption {
+	return func(e *Extractor) {
+		e.hashPrefixes = db
+	}
+}
+
+// Limits on the expressions of a URL, set by the Safe Browsing specification.
+const (
+	maxHostLabels   = 5 // Host suffixes start from the last 5 labels
+	maxPathPrefixes = 4 // The root and 3 directories
+)
+
+var errEmptyURL = errors.New("empty URL")
+
+// canonicalURL is a URL canonicalized for hash matching.
+type canonicalURL struct {
+	scheme, host, path, query string
+	hasQuery                  bool
+	ip                        bool
+}
+
+func (c canonicalURL) String() string {
+	s := c.scheme + "://" + c.host + c.path
+	if c.hasQuery {
+		s += "?" + c.query
+	}
+	return s
+}
+
+// CanonicalizeURL returns the canonical form of a URL or domain used for Safe Browsing
+// hash matching: tabs, line breaks, the fragment, userinfo and port are removed,
+// percent-escapes are decoded repeatedly, the host is lowercased with its dots
+// collapsed and IPv4 addresses in any notation normalized, "." and ".." path segments
+// are resolved and repeated slashes collapsed. Control characters, spaces, non-ASCII
+// bytes, '#' and '%' are then percent-escaped again. URLs without a scheme get http.
+//
+//	CanonicalizeURL("HTTP://www.Example.com.:80/a/./b/../c//d#top") // "http://www.example.com/a/c/d", nil
+//	CanonicalizeURL("http://3279880203/blah")                      // "http://195.127.0.11/blah", nil
+func CanonicalizeURL(raw string) (string, error) {
+	c, err := canonicalize(raw)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+func canonicalize(input string) (canonicalURL, error) {
+	raw := strings.NewReplacer("\t", "", "\r", "", "\n", "").Replace(strings.TrimSpace(input))
+	raw, _, _ = strings.Cut(raw, "#")
+	raw = unescapeRepeatedly(raw)
+	if raw == "" {
+		return canonicalURL{}, errEmptyURL
+	}
+
+	c := canonicalURL{scheme: "http"}
+	if scheme, rest, ok := strings.Cut(raw, "://"); ok && isScheme(scheme) {
+		c.scheme, raw = strings.ToLower(scheme), rest
+	}
+
+	authority := raw
+	if i := strings.IndexAny(raw, "/?"); i >= 0 {
+		authority, raw = raw[:i], raw[i:]
+	} else {
+		raw = ""
+	}
+	path, query, hasQuery := strings.Cut(raw, "?")
+
+	host := authority[strings.LastIndexByte(authority, '@')+1:]
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	host, c.ip = canonicalHost(host)
+	if host == "" {
+		return canonicalURL{}, fmt.Errorf("URL %q has no host", input)
+	}
+
+	c.host = escapeCanonical(host)
+	c.path = escapeCanonical(canonicalPath(path))
+	c.query, c.hasQuery = escapeCanonical(query), hasQuery
+	return c, nil
+}
+
+func isScheme(s string) bool {
+	if s == "" || !isASCIILetter(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if c := s[i]; !isASCIILetter(c) && !(c >= '0' && c <= '9') && c != '+' && c != '-' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// unescapeRepeatedly decodes percent-escapes until none are left, leaving invalid
+// ones alone.
+func unescapeRepeatedly(s string) string {
+	for {
+		unescaped := unescapePercent(s)
+		if unescaped == s {
+			return s
+		}
+		s = unescaped
+	}
+}
+
+func unescapePercent(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			v, _ := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			b.WriteByte(byte(v))
+			i += 2
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// escapeCanonical percent-escapes the bytes up to and including space, from 0x7F,
+// '#' and '%' with uppercase hex digits.
+func escapeCanonical(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7F || c == '#' || c == '%' {
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xF])
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalHost lowercases host, collapses its dots and normalizes IP addresses,
+// reporting whether it is one.
+func canonicalHost(host string) (string, bool) {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		if addr, err := netip.ParseAddr(host[1 : len(host)-1]); err == nil && addr.Is6() {
+			return "[" + addr.String() + "]", true
+		}
+	}
+
+	// ASCII only, strings.ToLower would replace invalid UTF-8 that is escaped as is
+	lower := []byte(host)
+	for i, c := range lower {
+		if c >= 'A' && c <= 'Z' {
+			lower[i] = c + 'a' - 'A'
+		}
+	}
+	labels := strings.FieldsFunc(string(lower), func(r rune) bool { return r == '.' })
+	host = strings.Join(labels, ".")
+	if addr, ok := parseLooseIPv4(host); ok {
+		return addr.String(), true
+	}
+	return host, false
+}
+
+// parseLooseIPv4 parses the IPv4 notations browsers accept: one to four parts in
+// decimal, octal with a leading 0 or hex with a leading 0x, the last part filling the
+// remaining bytes, as in "3279880203" or "0xc3.0177.11".
+func parseLooseIPv4(host string) (netip.Addr, bool) {
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return netip.Addr{}, false
+	}
+	var addr uint64
+	for i, part := range parts {
+		n, ok := parseIPv4Part(part)
+		if !ok {
+			return netip.Addr{}, false
+		}
+		if i < len(parts)-1 {
+			if n > 0xFF {
+				return netip.Addr{}, false
+			}
+			addr |= n << (8 * (3 - i))
+		} else {
+			if bits := 8 * (4 - i); bits < 32 && n >= 1<<bits {
+				return netip.Addr{}, false
+			}
+			addr |= n
+		}
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(addr))
+	return netip.AddrFrom4(b), true
+}
+
+func parseIPv4Part(part string) (uint64, bool) {
+	base, digits := 10, part
+	switch {
+	case len(part) > 2 && (part[:2] == "0x" || part[:2] == "0X"):
+		base, digits = 16, part[2:]
+	case len(part) > 1 && part[0] == '0':
+		base, digits = 8, part[1:]
+	}
+	// ParseUint alone would accept signs, underscores and other prefixes
+	for i := 0; i < len(digits); i++ {
+		if !isHex(digits[i]) {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseUint(digits, base, 32)
+	return n, err == nil
+}
+
+// canonicalPath resolves "." and ".." segments and collapses repeated slashes,
+// keeping a trailing slash.
+func canonicalPath(path string) string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, segment)
+		}
+	}
+	canonical := "/" + strings.Join(segments, "/")
+	if len(segments) > 0 && (strings.HasSuffix(path, "/") || strings.HasSuffix(path, "/.") || strings.HasSuffix(path, "/..")) {
+		canonical += "/"
+	}
+	return canonical
+}
+
+// URLExpressions returns the host-suffix/path-prefix expressions of the canonical form
+// of a URL or domain that Safe Browsing lists are matched against, most specific first.
+// Hosts are the exact host and up to 4 suffixes formed from the last 5 labels, never
+// the top-level domain alone; IP addresses are used as is. Paths are the exact path
+// with and without the query and up to 4 prefixes from the root.
+//
+//	URLExpressions("http://a.b.c/1/2.html?param=1")
+//	// a.b.c/1/2.html?param=1, a.b.c/1/2.html, a.b.c/, a.b.c/1/,
+//	// b.c/1/2.html?param=1, b.c/1/2.html, b.c/, b.c/1/
+func URLExpressions(raw string) ([]string, error) {
+	c, err := canonicalize(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []string{c.host}
+	if !c.ip {
+		labels := strings.Split(c.host, ".")
+		for i := max(1, len(labels)-maxHostLabels); i < len(labels)-1; i++ {
+			hosts = append(hosts, strings.Join(labels[i:], "."))
+		}
+	}
+
+	var paths []string
+	if c.hasQuery {
+		paths = append(paths, c.path+"?"+c.query)
+	}
+	paths = append(paths, c.path)
+	dirs := strings.Split(c.path, "/")
+	prefix := "/"
+	for i := 0; i < len(dirs)-1 && i < maxPathPrefixes; i++ {
+		if i > 0 {
+			prefix += dirs[i] + "/"
+		}
+		if prefix != c.path {
+			paths = append(paths, prefix)
+		}
+	}
+
+	expressions := make([]string, 0, len(hosts)*len(paths))
+	for _, host := range hosts {
+		for _, path := range paths {
+			expressions = append(expressions, host+path)
+		}
+	}
+	return expressions, nil
+}
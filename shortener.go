@@ -0,0 +1,121 @@
+package urlverify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// shortenerDomains are the registrable domains of well-known URL shorteners.
+var shortenerDomains = map[string]bool{
+	"bit.ly": true, "bitly.com": true, "j.mp": true, "t.co": true, "tinyurl.com": true,
+	"goo.gl": true, "ow.ly": true, "buff.ly": true, "is.gd": true, "v.gd": true,
+	"rebrand.ly": true, "cutt.ly": true, "shorturl.at": true, "tiny.cc": true, "rb.gy": true,
+	"t.ly": true, "bl.ink": true, "lnkd.in": true, "fb.me": true, "youtu.be": true,
+	"amzn.to": true, "amzn.eu": true, "a.co": true, "aka.ms": true, "trib.al": true,
+	"dlvr.it": true, "s.id": true, "qr.ae": true, "adf.ly": true, "shorte.st": true,
+	"soo.gd": true, "tr.im": true, "x.co": true, "y2u.be": true, "spoti.fi": true,
+	"apple.co": true, "wp.me": true, "flic.kr": true, "git.io": true, "tiny.one": true,
+	"clck.ru": true, "u.to": true, "surl.li": true, "short.gy": true, "shorturl.com": true,
+	"urlz.fr": true, "lnk.to": true, "ift.tt": true, "mcaf.ee": true, "db.tt": true,
+}
+
+// maxExpandHops limits the shortener redirects Expand follows.
+const maxExpandHops = 10
+
+// WithShorteners adds domains to the built-in list of URL shorteners that results are
+// marked with in ValidationResult.Shortener. A host matches if it or its registrable
+// domain is in the list.
+func WithShorteners(domains ...string) ValidatorOption {
+	return func(v *Validator) {
+		if v.shorteners == nil {
+			v.shorteners = make(map[string]bool)
+		}
+		for _, d := range domains {
+			if name, err := NormalizeURI(strings.TrimSuffix(d, ".")); err == nil {
+				v.shorteners[name] = true
+			}
+		}
+	}
+}
+
+// isShortener reports whether host, with the public suffix eTLD, belongs to a URL
+// shortener.
+func (v *Validator) isShortener(host, eTLD string) bool {
+	site := host
+	if rest, ok := strings.CutSuffix(host, eTLD); ok && strings.HasSuffix(rest, ".") {
+		site = host[strings.LastIndexByte(rest[:len(rest)-1], '.')+1:]
+	}
+	return shortenerDomains[site] || v.shorteners[site] || v.shorteners[host]
+}
+
+// Expand resolves a shortened URL to its destination with client, or
+// http.DefaultClient if client is nil, and returns the validated destination.
+// Redirects are followed as long as they lead to other shorteners; the destination
+// itself isn't requested. Requests use HEAD, falling back to GET for shorteners
+// that don't answer HEAD, and bodies aren't read. URLs that aren't shortened are
+// returned as they are, without any request.
+func Expand(ctx context.Context, client *http.Client, raw string) (ValidationResult, error) {
+	return defaultValidator.Expand(ctx, client, raw)
+}
+
+// Expand works like the package-level Expand, validating with v and using its
+// shortener list.
+func (v *Validator) Expand(ctx context.Context, client *http.Client, raw string) (ValidationResult, error) {
+	result := v.ValidateDomain(raw)
+	if !result.Valid {
+		return result, fmt.Errorf("expand %q: %s", raw, result.Reason)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	noRedirects := *client
+	noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	for hops := 0; result.Shortener; hops++ {
+		if hops == maxExpandHops {
+			return result, fmt.Errorf("expand %q: more than %d redirects", raw, maxExpandHops)
+		}
+		location, err := shortenerLocation(ctx, &noRedirects, result.URL.String())
+		if err != nil {
+			return result, fmt.Errorf("expand %q: %w", raw, err)
+		}
+		next, err := result.URL.Parse(location)
+		if err != nil {
+			return result, fmt.Errorf("expand %q: invalid redirect location %q: %w", raw, location, err)
+		}
+		if result = v.ValidateDomain(next.String()); !result.Valid {
+			return result, fmt.Errorf("expand %q: invalid destination %q: %s", raw, next, result.Reason)
+		}
+	}
+	return result, nil
+}
+
+// shortenerLocation returns where a shortened URL redirects to.
+func shortenerLocation(ctx context.Context, client *http.Client, target string) (string, error) {
+	var status int
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, target, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close() // The body isn't needed, only the Location header
+		status = resp.StatusCode
+
+		if location := resp.Header.Get("Location"); isRedirect(resp.StatusCode) && location != "" {
+			return location, nil
+		}
+		if resp.StatusCode < 400 {
+			break
+		}
+	}
+	return "", fmt.Errorf("%s didn't redirect, status %d", target, status)
+}
@@ -0,0 +1,121 @@
+package urlverify
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestShortenerFlag(t *testing.T) {
+	tests := []struct {
+		input     string
+		shortener bool
+	}{
+		{"https://bit.ly/3xYz", true},
+		{"t.co/abc", true},
+		{"https://www.tinyurl.com/abc", true},
+		{"https://youtu.be/dQw4w9WgXcQ", true},
+		{"https://example.com/bit.ly", false},
+		{"https://notbit.ly/abc", false},
+		{"http://192.0.2.1/", false},
+	}
+	for _, tt := range tests {
+		if r := ValidateDomain(tt.input); r.Shortener != tt.shortener {
+			t.Errorf("ValidateDomain(%q).Shortener = %v, want %v", tt.input, r.Shortener, tt.shortener)
+		}
+	}
+
+	v := NewValidator(WithShorteners("shrt.io", "go.example.com"))
+	for _, input := range []string{"https://shrt.io/x", "https://www.shrt.io/x", "https://go.example.com/x", "https://bit.ly/x"} {
+		if !v.ValidateDomain(input).Shortener {
+			t.Errorf("custom ValidateDomain(%q).Shortener = false", input)
+		}
+	}
+	if v.ValidateDomain("https://www.example.com/").Shortener {
+		t.Error("the registrable domain of a custom shortener host matched")
+	}
+}
+
+// newShortenerClient returns a client sending every request to a test server that
+// plays bit.ly, tinyurl.com and the destination site.
+func newShortenerClient(t *testing.T) (*http.Client, *atomic.Int32) {
+	var destinationHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host {
+		case "bit.ly":
+			switch r.URL.Path {
+			case "/nested":
+				http.Redirect(w, r, "http://tinyurl.com/final", http.StatusMovedPermanently)
+			case "/no-head":
+				if r.Method == http.MethodHead {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				http.Redirect(w, r, "https://example.org/page", http.StatusFound)
+			case "/loop":
+				http.Redirect(w, r, "/loop", http.StatusFound)
+			case "/bad":
+				http.Redirect(w, r, "http://not_valid/", http.StatusFound)
+			case "/dead":
+				http.NotFound(w, r)
+			default:
+				http.Redirect(w, r, "https://example.com/article?id=7", http.StatusMovedPermanently)
+			}
+		case "tinyurl.com":
+			http.Redirect(w, r, "https://example.net/landing", http.StatusFound)
+		default:
+			destinationHits.Add(1)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	addr := server.Listener.Addr().String()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	return client, &destinationHits
+}
+
+func TestExpand(t *testing.T) {
+	client, destinationHits := newShortenerClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"http://bit.ly/abc", "https://example.com/article?id=7"},
+		{"bit.ly/abc", "https://example.com/article?id=7"},
+		{"http://bit.ly/nested", "https://example.net/landing"},
+		{"http://bit.ly/no-head", "https://example.org/page"},
+		{"https://example.com/not-short", "https://example.com/not-short"},
+	}
+	for _, tt := range tests {
+		result, err := Expand(ctx, client, tt.input)
+		if err != nil {
+			t.Errorf("Expand(%q) error: %v", tt.input, err)
+			continue
+		}
+		if !result.Valid || result.Shortener || result.URL.String() != tt.want {
+			t.Errorf("Expand(%q) = %v %v, want %q", tt.input, result.URL, result.Valid, tt.want)
+		}
+	}
+	if n := destinationHits.Load(); n != 0 {
+		t.Errorf("destination requested %d times, want never", n)
+	}
+
+	for _, input := range []string{"http://bit.ly/loop", "http://bit.ly/bad", "http://bit.ly/dead", "not_valid"} {
+		if _, err := Expand(ctx, client, input); err == nil {
+			t.Errorf("Expand(%q) succeeded", input)
+		}
+	}
+	if _, err := Expand(ctx, client, "http://bit.ly/dead"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("Expand(dead) error = %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package urlverify
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxUnwrapDepth limits how many nested wrappers are unwrapped, so a wrapper pointing
+// to itself can't loop forever.
+const maxUnwrapDepth = 8
+
+// Unwrapper decodes the destination of a link-protection or redirect wrapper URL,
+// such as the url parameter of a Microsoft Safe Links URL. It returns false if u
+// isn't a wrapper it knows.
+type Unwrapper func(u *url.URL) (target string, ok bool)
+
+// Wrapper is a wrapper URL a match was unwrapped from.
+type Wrapper struct {
+	Name string // Name the Unwrapper that decoded it is registered under, e.g. "safelinks"
+	URL  string // The wrapper URL
+}
+
+// UnwrapRegistry holds the Unwrappers used to unwrap URLs, tried in the order they were
+// registered. The zero value is an empty registry ready to use; NewUnwrapRegistry
+// returns one with the built-in Unwrappers. A registry is safe for concurrent use once
+// configured.
+type UnwrapRegistry struct {
+	names      []string
+	unwrappers []Unwrapper
+}
+
+var defaultUnwrapRegistry = NewUnwrapRegistry()
+
+// NewUnwrapRegistry creates an UnwrapRegistry with the built-in Unwrappers:
+//
+//	safelinks   Microsoft Defender Safe Links, *.safelinks.protection.outlook.com/?url=
+//	proofpoint  Proofpoint URL Defense v1, v2 (/v2/url?u=) and v3 (/v3/__URL__;...)
+//	mimecast    Mimecast URL Protect, which only reveals the destination domain
+//	google      Google redirects, google.*/url?q=
+//	facebook    Facebook, Messenger and Instagram link shims, l.facebook.com/l.php?u=
+//	slack       Slack redirects, slack-redir.net/link?url=
+func NewUnwrapRegistry() *UnwrapRegistry {
+	r := &UnwrapRegistry{}
+	r.Register("safelinks", unwrapSafeLinks)
+	r.Register("proofpoint", unwrapProofpoint)
+	r.Register("mimecast", unwrapMimecast)
+	r.Register("google", unwrapGoogle)
+	r.Register("facebook", unwrapFacebook)
+	r.Register("slack", unwrapSlack)
+	return r
+}
+
+// Register adds an Unwrapper under name, replacing the one registered under the same
+// name if there is one.
+func (r *UnwrapRegistry) Register(name string, unwrap Unwrapper) {
+	for i, n := range r.names {
+		if n == name {
+			r.unwrappers[i] = unwrap
+			return
+		}
+	}
+	r.names = append(r.names, name)
+	r.unwrappers = append(r.unwrappers, unwrap)
+}
+
+// Unwrap returns the destination of a wrapper URL, unwrapping nested wrappers, and the
+// wrappers it was found in, outermost first. URLs that aren't wrappers are returned
+// unchanged with no wrappers.
+func (r *UnwrapRegistry) Unwrap(raw string) (target string, wrappers []Wrapper) {
+	target = raw
+	for len(wrappers) < maxUnwrapDepth {
+		u, err := ParseURL(target)
+		if err != nil {
+			break
+		}
+		next, name, ok := r.unwrapOnce(u)
+		if !ok {
+			break
+		}
+		wrappers = append(wrappers, Wrapper{Name: name, URL: target})
+		target = next
+	}
+	return target, wrappers
+}
+
+func (r *UnwrapRegistry) unwrapOnce(u *url.URL) (target, name string, ok bool) {
+	for i, unwrap := range r.unwrappers {
+		if target, ok := unwrap(u); ok && target != "" {
+			return strings.TrimSpace(target), r.names[i], true
+		}
+	}
+	return "", "", false
+}
+
+// Unwrap returns the destination of a wrapper URL using the built-in Unwrappers,
+// see UnwrapRegistry.Unwrap.
+func Unwrap(raw string) (target string, wrappers []Wrapper) {
+	return defaultUnwrapRegistry.Unwrap(raw)
+}
+
+// unwrapMatches replaces matches of wrapper URLs with their destination. Matches whose
+// destination isn't a valid URL are left as they were.
+func unwrapMatches(matches []Match, r *UnwrapRegistry, v *Validator) {
+	for i := range matches {
+		m := &matches[i]
+		target, wrappers := r.Unwrap(m.Text)
+		if len(wrappers) == 0 {
+			continue
+		}
+		result := v.ValidateDomain(target)
+		if !result.Valid {
+			continue
+		}
+		m.Text, m.Result, m.Wrappers = target, result, wrappers
+	}
+}
+
+// hostHasSuffix reports whether the host of u is domain or one of its subdomains.
+func hostHasSuffix(u *url.URL, domain string) bool {
+	host := strings.ToLower(u.Hostname())
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func unwrapSafeLinks(u *url.URL) (string, bool) {
+	if !hostHasSuffix(u, "safelinks.protection.outlook.com") && !hostHasSuffix(u, "safelinks.protection.office365.us") {
+		return "", false
+	}
+	target := u.Query().Get("url")
+	return target, target != ""
+}
+
+// proofpointRuns maps the character after "**" in a v3 URL to the number of
+// characters replaced.
+const proofpointRuns = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+func unwrapProofpoint(u *url.URL) (string, bool) {
+	if !hostHasSuffix(u, "urldefense.proofpoint.com") && !hostHasSuffix(u, "urldefense.com") {
+		return "", false
+	}
+
+	switch {
+	case strings.HasPrefix(u.Path, "/v1/"):
+		target := u.Query().Get("u")
+		return target, target != ""
+	case strings.HasPrefix(u.Path, "/v2/"):
+		// "-" stands for "%" and "_" for "/"
+		encoded := strings.NewReplacer("-", "%", "_", "/").Replace(u.Query().Get("u"))
+		target, err := url.PathUnescape(encoded)
+		return target, err == nil && target != ""
+	case strings.HasPrefix(u.Path, "/v3/__"):
+		return unwrapProofpointV3(u.String())
+	}
+	return "", false
+}
+
+// unwrapProofpointV3 decodes "https://urldefense.com/v3/__URL__;BYTES!!...". Characters
+// Proofpoint rewrote are replaced by "*" in URL, or by "**" and a run length for several
+// in a row; BYTES holds them base64 encoded.
+func unwrapProofpointV3(raw string) (string, bool) {
+	_, rest, _ := strings.Cut(raw, "/v3/__")
+	encoded, tail, ok := strings.Cut(rest, "__;")
+	if !ok {
+		encoded, ok = strings.CutSuffix(rest, "__")
+	}
+	if !ok || encoded == "" {
+		return "", false
+	}
+	encoded, err := url.PathUnescape(encoded)
+	if err != nil {
+		return "", false
+	}
+	if !strings.Contains(encoded, "*") {
+		return encoded, true
+	}
+
+	tail, _, _ = strings.Cut(tail, "!")
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(tail, "="))
+	if err != nil {
+		return "", false
+	}
+	replaced := []rune(string(decoded))
+
+	var b strings.Builder
+	for i := 0; i < len(encoded); i++ {
+		if encoded[i] != '*' {
+			b.WriteByte(encoded[i])
+			continue
+		}
+		n := 1
+		if strings.HasPrefix(encoded[i:], "**") && i+2 < len(encoded) {
+			run := strings.IndexByte(proofpointRuns, encoded[i+2])
+			if run < 0 {
+				return "", false
+			}
+			n = run + 2
+			i += 2
+		}
+		if n > len(replaced) {
+			return "", false
+		}
+		b.WriteString(string(replaced[:n]))
+		replaced = replaced[n:]
+	}
+	return b.String(), true
+}
+
+// unwrapMimecast returns the destination domain of a URL Protect link. The path is an
+// opaque token resolved by Mimecast, only the domain parameter tells where it leads.
+func unwrapMimecast(u *url.URL) (string, bool) {
+	if !hostHasSuffix(u, "mimecast.com") && !hostHasSuffix(u, "mimecastprotect.com") {
+		return "", false
+	}
+	if !strings.HasPrefix(u.Path, "/s/") {
+		return "", false
+	}
+	target := u.Query().Get("domain")
+	return target, target != ""
+}
+
+func unwrapGoogle(u *url.URL) (string, bool) {
+	if u.Path != "/url" {
+		return "", false
+	}
+	site, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(u.Hostname()))
+	if err != nil || !strings.HasPrefix(site, "google.") {
+		return "", false
+	}
+	query := u.Query()
+	target := query.Get("q")
+	if target == "" {
+		target = query.Get("url")
+	}
+	return target, target != ""
+}
+
+func unwrapFacebook(u *url.URL) (string, bool) {
+	switch strings.ToLower(u.Hostname()) {
+	case "l.facebook.com", "lm.facebook.com", "l.messenger.com", "l.instagram.com":
+	default:
+		return "", false
+	}
+	if u.Path != "/l.php" && u.Path != "/" && u.Path != "" {
+		return "", false
+	}
+	target := u.Query().Get("u")
+	return target, target != ""
+}
+
+func unwrapSlack(u *url.URL) (string, bool) {
+	if !hostHasSuffix(u, "slack-redir.net") || u.Path != "/link" {
+		return "", false
+	}
+	target := u.Query().Get("url")
+	return target, target != ""
+}
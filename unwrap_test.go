@@ -0,0 +1,125 @@
+package urlverify
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnwrap(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		target  string
+		wrapper string
+	}{
+		{"safe links", "https://nam12.safelinks.protection.outlook.com/?url=https%3A%2F%2Fexample.com%2Fpath%3Fa%3D1&data=05%7C01%7C&reserved=0", "https://example.com/path?a=1", "safelinks"},
+		{"proofpoint v1", "https://urldefense.proofpoint.com/v1/url?u=http%3A%2F%2Fexample.com%2Fa&k=abc", "http://example.com/a", "proofpoint"},
+		{"proofpoint v1 escaped plus", "https://urldefense.proofpoint.com/v1/url?u=http%3A%2F%2Fexample.com%2Fsearch%3Fq%3Da%252Bb%2Bc&k=abc", "http://example.com/search?q=a%2Bb+c", "proofpoint"},
+		{"proofpoint v2", "https://urldefense.proofpoint.com/v2/url?u=https-3A__example.com_path-3Fa-3D1&d=DwMFaQ&c=x&r=y&m=z&s=w&e=", "https://example.com/path?a=1", "proofpoint"},
+		{"proofpoint v3", "https://urldefense.com/v3/__https://example.com/path__;!!token!abc$", "https://example.com/path", "proofpoint"},
+		{"proofpoint v3 bytes", "https://urldefense.com/v3/__https://example.com/p?a=1*b=2**Ac__;JkBA!!token!abc$", "https://example.com/p?a=1&b=2@@c", "proofpoint"},
+		{"mimecast", "https://protect-eu.mimecast.com/s/AbCdEfGh?domain=example.com", "example.com", "mimecast"},
+		{"google", "https://www.google.com/url?q=https://example.com/&sa=D", "https://example.com/", "google"},
+		{"google country", "https://www.google.co.uk/url?sa=t&url=https%3A%2F%2Fexample.org%2F", "https://example.org/", "google"},
+		{"facebook", "https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.com%2F&h=AT0", "https://example.com/", "facebook"},
+		{"slack", "https://slack-redir.net/link?url=https%3A%2F%2Fexample.com%2Fdoc", "https://example.com/doc", "slack"},
+		{"not google", "https://www.notgoogle.com/url?q=https://example.com/", "", ""},
+		{"google search", "https://www.google.com/search?q=https://example.com/", "", ""},
+		{"plain", "https://example.com/?url=https://example.org/", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, wrappers := Unwrap(tt.input)
+			if tt.wrapper == "" {
+				if target != tt.input || len(wrappers) != 0 {
+					t.Errorf("Unwrap() = %q, %v, want it unchanged", target, wrappers)
+				}
+				return
+			}
+			if target != tt.target {
+				t.Errorf("Unwrap() target = %q, want %q", target, tt.target)
+			}
+			if want := []Wrapper{{tt.wrapper, tt.input}}; !reflect.DeepEqual(wrappers, want) {
+				t.Errorf("Unwrap() wrappers = %v, want %v", wrappers, want)
+			}
+		})
+	}
+}
+
+func TestUnwrapNested(t *testing.T) {
+	google := "https://www.google.com/url?q=" + url.QueryEscape("https://l.facebook.com/l.php?u="+url.QueryEscape("https://example.com/x"))
+	safeLinks := "https://eur01.safelinks.protection.outlook.com/?url=" + url.QueryEscape(google) + "&data=x"
+
+	target, wrappers := Unwrap(safeLinks)
+	if target != "https://example.com/x" {
+		t.Errorf("Unwrap() target = %q", target)
+	}
+	var names []string
+	for _, w := range wrappers {
+		names = append(names, w.Name)
+	}
+	if want := []string{"safelinks", "google", "facebook"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Unwrap() wrappers = %v, want %v", names, want)
+	}
+
+	// A wrapper pointing to itself stops at the depth limit
+	loop := "https://slack-redir.net/link?url=https%3A%2F%2Fslack-redir.net%2Flink"
+	if _, wrappers := Unwrap(loop); len(wrappers) > maxUnwrapDepth {
+		t.Errorf("Unwrap() unwrapped %d wrappers", len(wrappers))
+	}
+}
+
+func TestUnwrapRegistry(t *testing.T) {
+	r := &UnwrapRegistry{}
+	r.Register("go", func(u *url.URL) (string, bool) {
+		if u.Hostname() != "go.example.com" {
+			return "", false
+		}
+		return "https://" + strings.TrimPrefix(u.Path, "/"), true
+	})
+
+	target, wrappers := r.Unwrap("https://go.example.com/example.org/page")
+	if target != "https://example.org/page" || len(wrappers) != 1 || wrappers[0].Name != "go" {
+		t.Errorf("Unwrap() = %q, %v", target, wrappers)
+	}
+	if target, _ := r.Unwrap("https://www.google.com/url?q=https://example.com/"); target != "https://www.google.com/url?q=https://example.com/" {
+		t.Errorf("empty registry unwrapped %q", target)
+	}
+
+	// Registering under an existing name replaces the Unwrapper
+	r = NewUnwrapRegistry()
+	r.Register("google", func(*url.URL) (string, bool) { return "", false })
+	if _, wrappers := r.Unwrap("https://www.google.com/url?q=https://example.com/"); len(wrappers) != 0 {
+		t.Errorf("replaced Unwrapper still unwrapped: %v", wrappers)
+	}
+}
+
+func TestExtractorUnwrapping(t *testing.T) {
+	wrapper := "https://nam12.safelinks.protection.outlook.com/?url=https%3A%2F%2Fevil.example.net%2Flogin&data=05%7C01&reserved=0"
+	text := "Please sign in at " + wrapper + " or https://www.google.com/url?q=not-a-url today."
+
+	matches := NewExtractor(WithUnwrapping(nil)).FindAll(text)
+	if len(matches) != 2 {
+		t.Fatalf("FindAll() returned %d matches, want 2", len(matches))
+	}
+
+	m := matches[0]
+	if m.Text != "https://evil.example.net/login" || m.Result.ASCIIHost != "evil.example.net" {
+		t.Errorf("match = %q host %q, want the destination", m.Text, m.Result.ASCIIHost)
+	}
+	if len(m.Wrappers) != 1 || m.Wrappers[0].URL != wrapper || text[m.Start:m.End] != wrapper {
+		t.Errorf("match wrappers = %v, span %q", m.Wrappers, text[m.Start:m.End])
+	}
+	if len(matches[1].Wrappers) != 0 || !strings.HasPrefix(matches[1].Text, "https://www.google.com/") {
+		t.Errorf("invalid destination was unwrapped: %q", matches[1].Text)
+	}
+
+	deny, _ := NewDomainSet(".example.net")
+	got := NewExtractor(WithUnwrapping(nil), WithDenyList(deny)).ExtractAll(text)
+	if len(got) != 1 {
+		t.Errorf("deny list on destinations = %q", got)
+	}
+}
@@ -20,16 +20,31 @@
 package urlverify
 
 import (
+	"fmt"
 	"net"
+	"net/netip"
 	"net/url"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
-	"golang.org/x/net/idna"
 	"golang.org/x/net/publicsuffix"
 )
 
-var urlRegex = regexp.MustCompile(`(?i)https?://[^\s]+|(?:\[[0-9a-fA-F:]+\]|\d{1,3}(?:\.\d{1,3}){3}|[a-zA-Z0-9][-a-zA-Z0-9]*(?:\.[a-zA-Z0-9][-a-zA-Z0-9]*)+)(?::\d+)?(?:/[^\s]*)?`)
+// IPv6 literals may embed an IPv4 address and carry a zone, escaped as %25 (RFC 6874) or not.
+// Bare domain labels may contain letters and digits of any script, combining marks and
+// symbols (for emoji domains), but start with a letter or digit so that a symbol in front
+// of a domain isn't taken into it; labels are separated by '.' or one of the IDNA full stops.
+// Underscores are matched for the validator to judge, so "_dmarc.example.com" doesn't
+// yield "dmarc.example.com".
+// The regex stops after the scheme of a URL and after the host and port of a bare domain,
+// urlBounds finds where the rest of the URL ends.
+var urlRegex = regexp.MustCompile(`(?i)https?://|(?:\[[0-9a-fA-F:.]+(?:%[0-9A-Za-z_.~%-]+)?\]|\d{1,3}(?:\.\d{1,3}){3}|` + idnLabel + `(?:[.。．｡]` + idnLabel + `)+)(?::\d+)?`)
+
+const idnLabel = `[\p{L}\p{N}_][-\p{L}\p{N}\p{M}\p{So}\x{200D}_]*`
+
+// idnaDots replaces the full stops UTS #46 treats as label separators with '.'.
+var idnaDots = strings.NewReplacer("\u3002", ".", "\uFF0E", ".", "\uFF61", ".")
 
 type URLType int
 
@@ -57,31 +72,140 @@ func (t URLType) String() string {
 
 // ValidationResult represents the result of domain validation.
 type ValidationResult struct {
-	Valid  bool     // Whether the URL or domain is valid
-	Reason string   // Explanation of the validation result
-	Type   URLType  // Type of URL or domain
-	TLD    string   // The effective TLD, if applicable or an IP address
-	URL    *url.URL // Only set if the URL was successfully parsed
+	Valid  bool       // Whether the URL or domain is valid
+	Reason string     // Explanation of the validation result
+	Code   ReasonCode // Machine-readable form of Reason
+	Type   URLType    // Type of URL or domain
+	TLD    string     // The effective TLD, if applicable or an IP address
+	URL    *url.URL   // Only set if the URL was successfully parsed
+
+	ASCIIHost   string   // Normalized host in ASCII (Punycode) form, set for valid domains
+	UnicodeHost string   // Host in Unicode form, set for valid domains
+	IDNARule    IDNARule // The IDNA rule the host violated, if the conversion failed
+
+	Port            int    // Explicit port, 0 if the URL has none
+	DefaultPort     bool   // Whether the explicit port is the scheme's default, e.g. 443 for https
+	HasUserinfo     bool   // Whether the URL carries userinfo, as in "user:password@host"
+	Username        string // User name from the userinfo
+	PasswordPresent bool   // Whether the userinfo includes a password
+
+	Zone     string       // IPv6 zone, e.g. "eth0" for [fe80::1%25eth0]; TLD holds the address without it
+	IPPrefix netip.Prefix // Range the IP address falls into, see WithIPSet and WithIPAllowList
+
+	Shortener bool      // Whether the host belongs to a URL shortener such as bit.ly, see Expand
+	DGA       *DGAScore // Algorithmically generated domain score, see WithDGAScoring; nil if not scored
+}
+
+// Match represents a single valid URL or domain found in text.
+type Match struct {
+	Text   string           // The URL or domain exactly as it appeared in the text
+	Start  int              // Byte offset of the match in the text
+	End    int              // Byte offset just past the end of the match
+	Result ValidationResult // Validation result for the match
+
+	Confidence float64  // Likelihood that the match is meant as a URL or domain, from 0 to 1
+	Signals    []Signal // Heuristic hints the confidence is based on
+
+	// Encoded holds the original text when the match was decoded from an escaped form,
+	// in which case Text holds the decoded URL. See WithEscapeDecoding.
+	Encoded string
+
+	// Wrappers holds the link-protection and redirect URLs the match was unwrapped from,
+	// outermost first, in which case Text and Result are those of the destination.
+	// See WithUnwrapping.
+	Wrappers []Wrapper
+
+	// Embedded holds the URLs found in the query and fragment of the match.
+	// See WithEmbeddedURLs.
+	Embedded []EmbeddedURL
+
+	// Resolution holds the outcome of resolving the host, nil for IP addresses.
+	// See WithResolveCheck.
+	Resolution *Resolution
+
+	// Categories holds the categories of the site the host belongs to, such as
+	// CategoryPaste. See WithCategorizer.
+	Categories []Category
+
+	// HashMatches holds the hash prefix lists the URL is found in. See WithHashPrefixDB.
+	HashMatches []HashMatch
+
+	// FeedMatches holds the threat feed entries matching the URL, its host or its
+	// registrable domain. See WithFeedIndex.
+	FeedMatches []FeedMatch
 }
 
 // ExtractAll extracts and validates all URLs and domains from the given text,
 // returning them exactly as they appeared in the original text (without adding schema).
 func ExtractAll(text string) []string {
-	matches := urlRegex.FindAllString(text, -1)
-
+	// Only the matched text is kept, Match values are large
 	var validURLs []string
-	for _, raw := range matches {
-		raw = strings.TrimRight(raw, ".,)") // Strip trailing punctuation
-		if result := ValidateDomain(raw); result.Valid {
-			validURLs = append(validURLs, raw)
+	scanAll(text, defaultValidator, func(raw string, _ int, _ ValidationResult) {
+		validURLs = append(validURLs, raw)
+	})
+	if v6 := findBareIPv6(text, defaultValidator); v6 != nil {
+		// Rare enough to merge the slow way
+		validURLs = validURLs[:0]
+		for _, m := range findAll(text, defaultValidator) {
+			validURLs = append(validURLs, m.Text)
 		}
 	}
 
 	return validURLs
 }
 
+// FindAll works like ExtractAll but returns the position, validation result and
+// confidence of every match.
+func FindAll(text string) []Match {
+	matches := findAll(text, defaultValidator)
+	scoreMatches(text, matches)
+	return matches
+}
+
+func findAll(text string, v *Validator) []Match {
+	var matches []Match
+	scanAll(text, v, func(raw string, start int, result ValidationResult) {
+		matches = append(matches, Match{
+			Text:   raw,
+			Start:  start,
+			End:    start + len(raw),
+			Result: result,
+		})
+	})
+
+	return mergeIPv6(matches, findBareIPv6(text, v))
+}
+
+// scanAll calls found for every valid URL or domain the regex finds in text, in order.
+// Bare IPv6 addresses are left to findBareIPv6.
+func scanAll(text string, v *Validator, found func(raw string, start int, result ValidationResult)) {
+	for pos := 0; pos < len(text); {
+		loc := urlRegex.FindStringIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		s, e := urlBounds(text[pos+loc[0]:], loc[1]-loc[0])
+		start, end := pos+loc[0]+s, pos+loc[0]+e
+		pos = max(end, start+1)
+
+		raw := strings.TrimRight(text[start:end], ".,)") // Strip trailing punctuation
+		if raw == "" {
+			continue
+		}
+		if result := v.ValidateDomain(raw); result.Valid {
+			found(raw, start, result)
+		}
+	}
+}
+
 // ParseURL tries to parse a single URL or domain string and returns a pointer to url.URL structure and/or error.
+// IPv6 addresses may be written without brackets, and zones with an unescaped '%'.
 func ParseURL(raw string) (*url.URL, error) {
+	if host, ok := bareIPv6(raw); ok {
+		return url.Parse("http://" + host)
+	}
+	raw = escapeZone(raw)
+
 	// Try to parse as-is first
 	u, err := url.Parse(raw)
 
@@ -95,66 +219,144 @@ func ParseURL(raw string) (*url.URL, error) {
 }
 
 // NormalizeURI normalizes a URI by converting it to ASCII and lowercasing it.
+// The ideographic and full-width full stops (。．｡) are treated as dots.
 // Returns the normalized URI or an error if the conversion fails.
 func NormalizeURI(uri string) (string, error) {
-	uri, err := idna.ToASCII(uri)
-	if err != nil {
-		return "", err
-	}
+	return defaultValidator.NormalizeURI(uri)
+}
 
+// NormalizeURI normalizes a URI like the package-level NormalizeURI using the validator's IDNA profile.
+func (v *Validator) NormalizeURI(uri string) (string, error) {
 	// Lowercase uri for consistency.
 	// See https://datatracker.ietf.org/doc/html/rfc4343 - DNS considered case-insensitive, but publicsuffix don't handle .COM as valid icann.
-	uri = strings.ToLower(uri)
+	// Non-ASCII labels have to be lowercased before the Punycode conversion, КНИГА.РФ and книга.рф encode differently.
+	if !isASCII(uri) {
+		uri = idnaDots.Replace(uri)
+	}
+	uri, err := v.idna.ToASCII(strings.ToLower(uri))
+	if err != nil {
+		return "", err
+	}
 
 	return uri, nil
 }
 
 // ValidateDomain validates a single URL or domain string and returns detailed validation result.
 func ValidateDomain(raw string) ValidationResult {
+	return defaultValidator.ValidateDomain(raw)
+}
+
+// ValidateDomain validates a single URL or domain string using the validator's settings.
+func (v *Validator) ValidateDomain(raw string) ValidationResult {
 	u, err := ParseURL(raw)
 	if err != nil {
 		return ValidationResult{
 			Valid:  false,
 			Reason: "parse error: " + err.Error(),
+			Code:   ReasonParseError,
 			Type:   URLTypeInvalid,
 		}
 	}
 
+	var result ValidationResult
 	// Check if it's an IP address
-	if ip := net.ParseIP(u.Hostname()); ip != nil {
-		return ValidationResult{
+	host, zone, ok := splitZone(u.Hostname())
+	if ip := parseIP(host); ip != nil && ok {
+		result = ValidationResult{
 			Valid:  true,
 			Reason: "valid IP address",
 			Type:   URLTypeIP,
 			TLD:    ip.String(),
 			URL:    u,
+			Zone:   zone,
 		}
+	} else {
+		// Validate domain using publicsuffix
+		result = v.validateDomainName(u)
+	}
+	if !result.Valid {
+		return result
 	}
 
-	// Validate domain using publicsuffix
-	return validateDomainName(u)
+	port, err := parsePort(u)
+	if err != nil {
+		return ValidationResult{
+			Valid:  false,
+			Reason: err.Error(),
+			Code:   ReasonInvalidPort,
+			Type:   URLTypeInvalid,
+			TLD:    result.TLD,
+		}
+	}
+	setAuthority(&result, u, port)
+	if result.Type != URLTypeIP {
+		result.Shortener = v.isShortener(result.ASCIIHost, result.TLD)
+		if v.dgaScoring {
+			score := scoreLabel(registrableLabel(result.ASCIIHost, result.TLD))
+			result.DGA = &score
+		}
+	}
+	if v.ipSet != nil {
+		lookupIP(v.ipSet, &result)
+	}
+
+	return result
+}
+
+// parseIP parses host like net.ParseIP, skipping hosts that can't be an address
+// without the cost of the error.
+func parseIP(host string) net.IP {
+	if host == "" || !isDigit(host[0]) && !strings.Contains(host, ":") {
+		return nil
+	}
+	return net.ParseIP(host)
 }
 
 // validateDomainName validates a domain name using the public suffix list.
-func validateDomainName(url *url.URL) ValidationResult {
-	hostname := url.Hostname()
+func (v *Validator) validateDomainName(url *url.URL) ValidationResult {
+	hostname := strings.TrimSuffix(url.Hostname(), ".") // A fully qualified name ends with the root
 
 	// Handle edge cases first
 	if hostname == "" {
 		return ValidationResult{
 			Valid:  false,
 			Reason: "empty hostname",
+			Code:   ReasonEmptyHost,
 			Type:   URLTypeInvalid,
 		}
 	}
 
 	var err error
-	hostname, err = NormalizeURI(hostname)
+	hostname, err = v.NormalizeURI(hostname)
 	if err != nil {
+		rule, code := v.idnaRule(err, url.Hostname()), ReasonIDNA
+		if rule == IDNARulePunycode || rule == IDNARuleInvalidPuny {
+			code = ReasonInvalidPunycode
+		}
 		return ValidationResult{
-			Valid:  false,
-			Reason: "invalid domain name: " + err.Error(),
-			Type:   URLTypeInvalid,
+			Valid:    false,
+			Reason:   "invalid domain name: " + err.Error(),
+			Code:     code,
+			Type:     URLTypeInvalid,
+			IDNARule: rule,
+		}
+	}
+	unicodeHost := v.unicodeHost(hostname)
+
+	// Checked on the input too, as mapping turns some symbols into letters ('™' into "tm")
+	for _, host := range [...]string{url.Hostname(), unicodeHost} {
+		if isASCII(host) {
+			continue
+		}
+		if i := strings.IndexFunc(host, isNonEmojiSymbol); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(host[i:])
+			return ValidationResult{
+				Valid:    false,
+				Reason:   fmt.Sprintf("invalid domain name: disallowed symbol %q", r),
+				Code:     ReasonIDNA,
+				Type:     URLTypeInvalid,
+				IDNARule: IDNARuleDisallowed,
+			}
 		}
 	}
 
@@ -163,6 +365,16 @@ func validateDomainName(url *url.URL) ValidationResult {
 		return ValidationResult{
 			Valid:  false,
 			Reason: "no valid TLD found",
+			Code:   ReasonNoTLD,
+			Type:   URLTypeInvalid,
+		}
+	}
+
+	if code, reason := v.checkStructure(hostname); code != ReasonValid {
+		return ValidationResult{
+			Valid:  false,
+			Reason: reason,
+			Code:   code,
 			Type:   URLTypeInvalid,
 		}
 	}
@@ -173,10 +385,24 @@ func validateDomainName(url *url.URL) ValidationResult {
 		return ValidationResult{
 			Valid:  false,
 			Reason: "no valid TLD found",
+			Code:   ReasonNoTLD,
 			Type:   URLTypeInvalid,
 		}
 	}
 
+	// Checked after the TLD, an unknown TLD is the more fundamental problem
+	if code, reason := v.checkCharacters(hostname); code != ReasonValid {
+		if icann || isICANNBased(eTLD) {
+			return ValidationResult{
+				Valid:  false,
+				Reason: reason,
+				Code:   code,
+				Type:   URLTypeInvalid,
+				TLD:    eTLD,
+			}
+		}
+	}
+
 	if icann {
 		return ValidationResult{
 			Valid:  true,
@@ -184,31 +410,45 @@ func validateDomainName(url *url.URL) ValidationResult {
 			Type:   URLTypeICANN,
 			TLD:    eTLD,
 			URL:    url,
+
+			ASCIIHost:   hostname,
+			UnicodeHost: unicodeHost,
 		}
 	}
 
 	// For non-ICANN eTLD, check if it's built on a valid ICANN TLD
 	// e.g., "foo.dyndns.org" -> eTLD is "dyndns.org", check if ".org" is ICANN
-	if strings.Contains(eTLD, ".") {
-		parts := strings.Split(eTLD, ".")
-		actualTLD := parts[len(parts)-1]
-		// Test if this actual TLD is an ICANN TLD
-		testDomain := "test." + actualTLD
-		if _, testICANN := publicsuffix.PublicSuffix(testDomain); testICANN {
-			return ValidationResult{
-				Valid:  true,
-				Reason: "valid domain built on ICANN TLD",
-				Type:   URLTypeNonICANN,
-				TLD:    eTLD,
-				URL:    url,
-			}
+	if isICANNBased(eTLD) {
+		return ValidationResult{
+			Valid:  true,
+			Reason: "valid domain built on ICANN TLD",
+			Type:   URLTypeNonICANN,
+			TLD:    eTLD,
+			URL:    url,
+
+			ASCIIHost:   hostname,
+			UnicodeHost: unicodeHost,
 		}
 	}
 
 	return ValidationResult{
 		Valid:  false,
 		Reason: "invalid or non-ICANN TLD",
+		Code:   ReasonInvalidTLD,
 		Type:   URLTypeInvalid,
 		TLD:    eTLD,
 	}
 }
+
+// isICANNBased reports whether a private eTLD such as "dyndns.org" sits under an ICANN TLD.
+func isICANNBased(eTLD string) bool {
+	if !strings.Contains(eTLD, ".") {
+		return false
+	}
+	parts := strings.Split(eTLD, ".")
+	actualTLD := parts[len(parts)-1]
+	// Test if this actual TLD is an ICANN TLD
+	testDomain := "test." + actualTLD
+	_, testICANN := publicsuffix.PublicSuffix(testDomain)
+	return testICANN
+}
@@ -206,6 +206,97 @@ Test case-insensitive URL schemes:
 	}
 }
 
+func TestFindAllPositions(t *testing.T) {
+	text := "Visit example.com, and also (https://google.com/search?q=test)."
+
+	result := FindAll(text)
+	expected := []string{"example.com", "https://google.com/search?q=test"}
+
+	if len(result) != len(expected) {
+		t.Errorf("FindAll() returned %d results, want %d", len(result), len(expected))
+		return
+	}
+
+	for i, want := range expected {
+		if result[i].Text != want || text[result[i].Start:result[i].End] != want {
+			t.Errorf("FindAll() result[%d] = %q at %d:%d, want %q", i, result[i].Text, result[i].Start, result[i].End, want)
+		}
+	}
+}
+
+func TestExtractAllBareIDN(t *testing.T) {
+	text := `
+- книга.рф
+- скачать с www.пример.рус/путь
+- 스타벅스코리아.com
+- مثال.السعودية
+- 日本語。jp and ｗｗｗ．例え．jp
+- bücher.de:8080/index.html
+- i❤️.ws
+- фото.невалидный
+`
+
+	expected := []string{
+		"книга.рф",
+		"www.пример.рус/путь",
+		"스타벅스코리아.com",
+		"مثال.السعودية",
+		"日本語。jp",
+		"ｗｗｗ．例え．jp",
+		"bücher.de:8080/index.html",
+		"i❤️.ws",
+	}
+
+	result := ExtractAll(text)
+
+	if len(result) != len(expected) {
+		t.Errorf("ExtractAll() returned %d results, want %d", len(result), len(expected))
+		t.Logf("Got: %v", result)
+		t.Logf("Expected: %v", expected)
+		return
+	}
+
+	for i, want := range expected {
+		if result[i] != want {
+			t.Errorf("ExtractAll() result[%d] = %q, want %q", i, result[i], want)
+		}
+	}
+
+	// IDN domains validate the same way as their ASCII forms
+	for _, domain := range []string{"книга。рф", "КНИГА.РФ", "xn--80atjc.xn--p1ai"} {
+		result := ValidateDomain(domain)
+		if !result.Valid || result.Type != URLTypeICANN || result.TLD != "xn--p1ai" {
+			t.Errorf("ValidateDomain(%q) = {%v %s %q}, want valid ICANN domain with TLD xn--p1ai", domain, result.Valid, result.Type, result.TLD)
+		}
+	}
+}
+
+func TestFindAllSymbolPrefixedDomains(t *testing.T) {
+	text := "★example.com ✅example.com ©example.com ™example.com"
+
+	result := FindAll(text)
+	if len(result) != 4 {
+		t.Fatalf("FindAll(%q) returned %d matches, want 4: %v", text, len(result), result)
+	}
+	for i, m := range result {
+		if m.Text != "example.com" || m.Result.ASCIIHost != "example.com" {
+			t.Errorf("FindAll() result[%d] = %q (host %q), want example.com", i, m.Text, m.Result.ASCIIHost)
+		}
+	}
+
+	// Symbols other than emoji are rejected anywhere in a label
+	for _, domain := range []string{"example★.com", "http://©example.com", "https://example™.com", "xn--example-nja.com", "i★.ws"} {
+		if result := ValidateDomain(domain); result.Valid || result.IDNARule != IDNARuleDisallowed {
+			t.Errorf("ValidateDomain(%q) = {%v %q %q}, want invalid with rule P1", domain, result.Valid, result.Reason, result.IDNARule)
+		}
+	}
+	for _, domain := range []string{"i❤.ws", "https://💩.la", "https://✅.example.com"} {
+		if result := ValidateDomain(domain); !result.Valid {
+			t.Errorf("ValidateDomain(%q) invalid: %s", domain, result.Reason)
+		}
+	}
+}
+
 // Benchmark data
 var (
 	// Text without any URLs or domains
@@ -305,6 +396,14 @@ func BenchmarkExtractAll_Mixed(b *testing.B) {
 	}
 }
 
+// BenchmarkFindAll_ManyURLs measures the cost of full matches with confidence scores
+func BenchmarkFindAll_ManyURLs(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		result := FindAll(textWithManyURLs)
+		_ = result
+	}
+}
+
 // BenchmarkValidateDomain_ICANN tests validation of ICANN domains
 func BenchmarkValidateDomain_ICANN(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,317 @@
+package urlverify
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/secure/bidirule"
+	"golang.org/x/text/unicode/norm"
+)
+
+// IDNAProfile selects how internationalized domain names are processed, see UTS #46.
+type IDNAProfile int
+
+const (
+	IDNAPunycode     IDNAProfile = iota // Raw Punycode conversion without validation, the default
+	IDNALookup                          // UTS #46 mapping and validation for looking up names
+	IDNARegistration                    // Strict validation for registering names, no mapping (RFC 5891)
+	IDNADisplay                         // Nontransitional UTS #46 mapping for displaying names
+)
+
+func (p IDNAProfile) String() string {
+	switch p {
+	case IDNAPunycode:
+		return "Punycode"
+	case IDNALookup:
+		return "Lookup"
+	case IDNARegistration:
+		return "Registration"
+	case IDNADisplay:
+		return "Display"
+	default:
+		return "Unknown"
+	}
+}
+
+// IDNARule identifies the UTS #46 processing step a domain name failed,
+// using the codes of the UTS #46 conformance tests.
+type IDNARule string
+
+const (
+	IDNARuleNone          IDNARule = ""
+	IDNARuleDisallowed    IDNARule = "P1" // Disallowed code point, including STD3 violations such as '_'
+	IDNARuleNotNFC        IDNARule = "V1" // Label is not in Unicode Normalization Form C
+	IDNARuleHyphen34      IDNARule = "V2" // Hyphens in the third and fourth positions
+	IDNARuleHyphenEdge    IDNARule = "V3" // Label starts or ends with a hyphen
+	IDNARuleLeadingMark   IDNARule = "V5" // Label starts with a combining mark
+	IDNARuleInvalidPuny   IDNARule = "V6" // Punycode label decodes to disallowed code points
+	IDNARulePunycode      IDNARule = "A3" // Malformed Punycode
+	IDNARuleLength        IDNARule = "A4" // Empty label, label over 63 or name over 253 octets
+	IDNARuleBidi          IDNARule = "B"  // Violates the Bidi rule of RFC 5893
+	IDNARuleContextJ      IDNARule = "C"  // Invalid zero width joiner or non-joiner (CONTEXTJ)
+	IDNARuleUnknownReason IDNARule = "?"  // The conversion failed for another reason
+)
+
+// Validator validates URLs and domains with configurable processing.
+// The zero value is not usable, create validators with NewValidator.
+// A Validator is safe for concurrent use.
+type Validator struct {
+	idna *idna.Profile
+
+	profile      IDNAProfile
+	transitional *bool
+	std3         *bool
+	bidi         bool
+	dnsNames     bool
+	ipSet        *IPSet
+	shorteners   map[string]bool
+	dgaScoring   bool
+}
+
+// ValidatorOption configures a Validator.
+type ValidatorOption func(*Validator)
+
+var defaultValidator = NewValidator()
+
+// NewValidator creates a Validator with the given options. Without options it behaves
+// like the package-level ValidateDomain.
+func NewValidator(opts ...ValidatorOption) *Validator {
+	v := &Validator{}
+	for _, opt := range opts {
+		opt(v)
+	}
+	v.idna = v.idnaProfile()
+	return v
+}
+
+// WithIDNAProfile selects the IDNA processing profile, IDNAPunycode by default.
+func WithIDNAProfile(profile IDNAProfile) ValidatorOption {
+	return func(v *Validator) {
+		v.profile = profile
+	}
+}
+
+// WithTransitional selects transitional processing, which maps deviation characters
+// such as 'ß' to their IDNA2003 replacements, or nontransitional processing.
+// It only affects the mapping profiles.
+func WithTransitional(transitional bool) ValidatorOption {
+	return func(v *Validator) {
+		v.transitional = &transitional
+	}
+}
+
+// WithSTD3Rules enables or disables the STD3 ASCII rules, which only allow letters,
+// digits and hyphens in labels. The mapping profiles enable them by default.
+func WithSTD3Rules(enable bool) ValidatorOption {
+	return func(v *Validator) {
+		v.std3 = &enable
+	}
+}
+
+// WithBidiRule enables the Bidi rule of RFC 5893 for right-to-left labels.
+// All profiles except IDNAPunycode check it anyway.
+func WithBidiRule() ValidatorOption {
+	return func(v *Validator) {
+		v.bidi = true
+	}
+}
+
+// WithDNSNames validates DNS names instead of host names: labels may contain underscores,
+// as in "_dmarc.example.com" or "_sip._tcp.example.com". The STD3 rules are disabled
+// unless WithSTD3Rules says otherwise.
+func WithDNSNames() ValidatorOption {
+	return func(v *Validator) {
+		v.dnsNames = true
+	}
+}
+
+// WithIPSet makes the Validator report the range of set an IP address falls into
+// in ValidationResult.IPPrefix.
+func WithIPSet(set *IPSet) ValidatorOption {
+	return func(v *Validator) {
+		v.ipSet = set
+	}
+}
+
+func (v *Validator) idnaProfile() *idna.Profile {
+	if v.dnsNames && v.std3 == nil {
+		std3 := false
+		v.std3 = &std3
+	}
+
+	var opts []idna.Option
+	switch v.profile {
+	case IDNALookup:
+		if v.transitional == nil && v.std3 == nil {
+			return idna.Lookup
+		}
+		opts = append(opts, idna.MapForLookup(), idna.BidiRule())
+	case IDNARegistration:
+		if v.transitional == nil && v.std3 == nil {
+			return idna.Registration
+		}
+		opts = append(opts, idna.ValidateForRegistration())
+	case IDNADisplay:
+		if v.transitional == nil && v.std3 == nil {
+			return idna.Display
+		}
+		opts = append(opts, idna.MapForLookup(), idna.BidiRule(), idna.Transitional(false))
+	default:
+		if !v.bidi && v.std3 == nil {
+			return idna.Punycode
+		}
+	}
+
+	if v.transitional != nil {
+		opts = append(opts, idna.Transitional(*v.transitional))
+	}
+	if v.std3 != nil {
+		opts = append(opts, idna.StrictDomainName(*v.std3))
+	}
+	if v.bidi {
+		opts = append(opts, idna.BidiRule())
+	}
+	return idna.New(opts...)
+}
+
+// ToUnicode converts a domain name to its Unicode form, the counterpart of NormalizeURI.
+func ToUnicode(uri string) (string, error) {
+	return defaultValidator.ToUnicode(uri)
+}
+
+// ToUnicode converts a domain name to its Unicode form using the validator's IDNA profile.
+func (v *Validator) ToUnicode(uri string) (string, error) {
+	return v.idna.ToUnicode(strings.ToLower(idnaDots.Replace(uri)))
+}
+
+// unicodeHost returns the Unicode form of a normalized ASCII host, or the host itself
+// if it can't be converted.
+func (v *Validator) unicodeHost(host string) string {
+	if !strings.Contains(host, "xn--") {
+		return host
+	}
+	if u, err := v.ToUnicode(host); err == nil {
+		return u
+	}
+	return host
+}
+
+// idnaRule works out which IDNA rule host violated given the conversion error.
+// The idna package doesn't expose the rule, so the checks are repeated here.
+func (v *Validator) idnaRule(err error, host string) IDNARule {
+	if err == nil {
+		return IDNARuleNone
+	}
+
+	host = strings.ToLower(idnaDots.Replace(host))
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	rtl := false
+	for _, label := range labels {
+		if label == "" {
+			return IDNARuleLength
+		}
+
+		if strings.HasPrefix(label, "xn--") {
+			decoded, err := idna.Punycode.ToUnicode(label)
+			if err != nil || decoded == label {
+				return IDNARulePunycode
+			}
+			if !norm.NFC.IsNormalString(decoded) {
+				return IDNARuleNotNFC
+			}
+			label = decoded
+		}
+
+		if len(label) >= 4 && label[2:4] == "--" {
+			return IDNARuleHyphen34
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return IDNARuleHyphenEdge
+		}
+		if r, _ := utf8.DecodeRuneInString(label); unicode.Is(unicode.M, r) {
+			return IDNARuleLeadingMark
+		}
+		if strings.ContainsAny(label, "\u200c\u200d") {
+			return IDNARuleContextJ
+		}
+		if a, err := idna.Punycode.ToASCII(label); err == nil && len(a) > 63 {
+			return IDNARuleLength
+		}
+		if strings.IndexFunc(label, isRTL) >= 0 {
+			rtl = true
+		}
+	}
+
+	if rtl {
+		for _, label := range labels {
+			if u, err := idna.Punycode.ToUnicode(label); err == nil && !bidirule.ValidString(u) {
+				return IDNARuleBidi
+			}
+		}
+	}
+	// The code points are converted one by one, after a letter in case of combining
+	// marks; the label rules were ruled out above
+	for _, r := range host {
+		if r == '.' || r == '-' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			continue
+		}
+		label := string(r)
+		if unicode.Is(unicode.M, r) {
+			label = "a" + label
+		}
+		if _, err := v.idna.ToASCII(label); err != nil {
+			return IDNARuleDisallowed
+		}
+	}
+	if len(host) > 253 {
+		return IDNARuleLength
+	}
+	if strings.Contains(host, "xn--") {
+		return IDNARuleInvalidPuny
+	}
+
+	return IDNARuleUnknownReason
+}
+
+func isRTL(r rune) bool {
+	return unicode.In(r, unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// emojiSymbols are the symbols Unicode marks as emoji (Emoji=Yes), less the letterlike
+// and punctuation ones such as '©' and '™'. They are the only symbols registries
+// accept in domain names, which UTS #46 otherwise lets through as valid (NV8).
+var emojiSymbols = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x2194, 0x2199, 1}, {0x21a9, 0x21aa, 1}, {0x231a, 0x231b, 1}, {0x2328, 0x2328, 1},
+		{0x23cf, 0x23cf, 1}, {0x23e9, 0x23f3, 1}, {0x23f8, 0x23fa, 1}, {0x24c2, 0x24c2, 1},
+		{0x25aa, 0x25ab, 1}, {0x25b6, 0x25b6, 1}, {0x25c0, 0x25c0, 1}, {0x25fb, 0x25fe, 1},
+		{0x2600, 0x2604, 1}, {0x260e, 0x260e, 1}, {0x2611, 0x2611, 1}, {0x2614, 0x2615, 1},
+		{0x2618, 0x2618, 1}, {0x261d, 0x261d, 1}, {0x2620, 0x2620, 1}, {0x2622, 0x2623, 1},
+		{0x2626, 0x2626, 1}, {0x262a, 0x262a, 1}, {0x262e, 0x262f, 1}, {0x2638, 0x263a, 1},
+		{0x2640, 0x2640, 1}, {0x2642, 0x2642, 1}, {0x2648, 0x2653, 1}, {0x265f, 0x2660, 1},
+		{0x2663, 0x2663, 1}, {0x2665, 0x2666, 1}, {0x2668, 0x2668, 1}, {0x267b, 0x267b, 1},
+		{0x267e, 0x267f, 1}, {0x2692, 0x2697, 1}, {0x2699, 0x2699, 1}, {0x269b, 0x269c, 1},
+		{0x26a0, 0x26a1, 1}, {0x26a7, 0x26a7, 1}, {0x26aa, 0x26ab, 1}, {0x26b0, 0x26b1, 1},
+		{0x26bd, 0x26be, 1}, {0x26c4, 0x26c5, 1}, {0x26c8, 0x26c8, 1}, {0x26ce, 0x26cf, 1},
+		{0x26d1, 0x26d1, 1}, {0x26d3, 0x26d4, 1}, {0x26e9, 0x26ea, 1}, {0x26f0, 0x26f5, 1},
+		{0x26f7, 0x26fa, 1}, {0x26fd, 0x26fd, 1}, {0x2702, 0x2702, 1}, {0x2705, 0x2705, 1},
+		{0x2708, 0x270d, 1}, {0x270f, 0x270f, 1}, {0x2712, 0x2712, 1}, {0x2714, 0x2714, 1},
+		{0x2716, 0x2716, 1}, {0x271d, 0x271d, 1}, {0x2721, 0x2721, 1}, {0x2728, 0x2728, 1},
+		{0x2733, 0x2734, 1}, {0x2744, 0x2744, 1}, {0x2747, 0x2747, 1}, {0x274c, 0x274c, 1},
+		{0x274e, 0x274e, 1}, {0x2753, 0x2755, 1}, {0x2757, 0x2757, 1}, {0x2763, 0x2764, 1},
+		{0x2795, 0x2797, 1}, {0x27a1, 0x27a1, 1}, {0x27b0, 0x27b0, 1}, {0x27bf, 0x27bf, 1},
+		{0x2934, 0x2935, 1}, {0x2b05, 0x2b07, 1}, {0x2b1b, 0x2b1c, 1}, {0x2b50, 0x2b50, 1},
+		{0x2b55, 0x2b55, 1}, {0x3030, 0x3030, 1}, {0x303d, 0x303d, 1}, {0x3297, 0x3297, 1},
+		{0x3299, 0x3299, 1},
+	},
+	R32: []unicode.Range32{
+		{0x1f000, 0x1faff, 1},
+	},
+}
+
+// isNonEmojiSymbol reports whether r is a symbol outside ASCII that isn't an emoji.
+func isNonEmojiSymbol(r rune) bool {
+	return r >= utf8.RuneSelf && unicode.IsSymbol(r) && !unicode.Is(emojiSymbols, r)
+}
@@ -0,0 +1,73 @@
+package urlverify
+
+import (
+	"testing"
+)
+
+func TestValidatorIDNAProfiles(t *testing.T) {
+	lookup := NewValidator(WithIDNAProfile(IDNALookup))
+
+	tests := []struct {
+		validator   *Validator
+		input       string
+		valid       bool
+		rule        IDNARule
+		asciiHost   string
+		description string
+	}{
+		{defaultValidator, "not_a_valid_domain.com", false, IDNARuleNone, "", "host names can't contain underscores"},
+		{lookup, "not_a_valid_domain.com", false, IDNARuleDisallowed, "", "lookup enforces STD3"},
+		{NewValidator(WithIDNAProfile(IDNALookup), WithDNSNames()), "not_a_valid_domain.com", true, IDNARuleNone, "not_a_valid_domain.com", "lookup of DNS names"},
+		{lookup, "ab--cd.com", false, IDNARuleHyphen34, "", "hyphens in positions 3 and 4"},
+		{lookup, "ex\u2488ample.com", false, IDNARuleDisallowed, "", "disallowed code point"},
+		{NewValidator(WithIDNAProfile(IDNARegistration)), "\uff45xample.com", false, IDNARuleDisallowed, "", "registration rejects code points that map"},
+		{lookup, "-abc.com", false, IDNARuleHyphenEdge, "", "leading hyphen"},
+		{lookup, "a‍b.com", false, IDNARuleContextJ, "", "zero width joiner outside CONTEXTJ"},
+		{lookup, "abא.com", false, IDNARuleBidi, "", "mixed direction label"},
+		{lookup, "xn--zz.com", false, IDNARulePunycode, "", "malformed punycode"},
+		{lookup, "́abc.com", false, IDNARuleLeadingMark, "", "leading combining mark"},
+		{lookup, "faß.de", true, IDNARuleNone, "xn--fa-hia.de", "nontransitional keeps sharp s"},
+		{NewValidator(WithIDNAProfile(IDNALookup), WithTransitional(true)), "faß.de", true, IDNARuleNone, "fass.de", "transitional maps sharp s"},
+		{NewValidator(WithIDNAProfile(IDNARegistration)), "ab--cd.com", false, IDNARuleHyphen34, "", "registration"},
+		{defaultValidator, "ab--cd.com", true, IDNARuleNone, "ab--cd.com", "punycode profile allows hyphens in positions 3 and 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			result := tt.validator.ValidateDomain(tt.input)
+
+			if result.Valid != tt.valid {
+				t.Errorf("ValidateDomain(%q) valid = %v, want %v (%s)", tt.input, result.Valid, tt.valid, result.Reason)
+			}
+			if result.IDNARule != tt.rule {
+				t.Errorf("ValidateDomain(%q) IDNA rule = %q, want %q", tt.input, result.IDNARule, tt.rule)
+			}
+			if result.ASCIIHost != tt.asciiHost {
+				t.Errorf("ValidateDomain(%q) ASCII host = %q, want %q", tt.input, result.ASCIIHost, tt.asciiHost)
+			}
+		})
+	}
+}
+
+func TestValidatorUnicodeForms(t *testing.T) {
+	for _, input := range []string{"https://книга.рф/path", "xn--80afohp.xn--p1ai", "КНИГА。РФ"} {
+		result := ValidateDomain(input)
+		if result.ASCIIHost != "xn--80afohp.xn--p1ai" || result.UnicodeHost != "книга.рф" {
+			t.Errorf("ValidateDomain(%q) hosts = %q, %q, want %q, %q", input, result.ASCIIHost, result.UnicodeHost, "xn--80afohp.xn--p1ai", "книга.рф")
+		}
+	}
+
+	unicode, err := ToUnicode("xn--bcher-kva.de")
+	if err != nil || unicode != "bücher.de" {
+		t.Errorf("ToUnicode() = %q, %v, want %q", unicode, err, "bücher.de")
+	}
+}
+
+func TestExtractorWithValidator(t *testing.T) {
+	text := "Hosts: good.example.com, ab--cd.example.com"
+
+	result := NewExtractor(WithValidator(NewValidator(WithIDNAProfile(IDNALookup)))).ExtractAll(text)
+	if len(result) != 1 || result[0] != "good.example.com" {
+		t.Errorf("Extractor.ExtractAll() = %v, want [good.example.com]", result)
+	}
+}